@@ -0,0 +1,241 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Package admin provides an http.Handler to inspect and change the
+// configuration of mlog Loggers at runtime.
+//
+// Example usage:
+//
+//	h := admin.NewHandler()
+//	h.Register("db", dbLogger)
+//	debugMux.Handle("/debug/mlog", h)
+//
+// A GET request returns the flags, minimum level and Emitter of the default
+// Logger (named "default") and of each registered Logger, as json:
+//
+//	{"default": {"flags": "FlagSet(Llevel|Lsort|Ltimestamp)", "level": "INFO", "emitter": "FormatWriterStructured"}}
+//
+// A PUT or POST request changes the Logger named by the "logger" parameter
+// (the default Logger if not set), using the following form parameters,
+// and returns its updated configuration:
+//
+//	flags    replace the flags, as in "Llevel|Ltimestamp" (or "" for none)
+//	enable   enable flags, as in "Ldebug,Lshortfile"
+//	disable  disable flags, as in "Ldebug"
+//	level    set the minimum level: debug, info, warn, error or fatal
+//	emitter  set the Emitter: structured, json or plain
+//
+// For example:
+//
+//	curl -X PUT 'http://localhost:6060/debug/mlog?enable=Ldebug&emitter=json'
+//
+// Changes apply to all Loggers sharing the output of the changed Logger, such
+// as its children created with Logger.With. For a named Logger with flags or
+// a level set with mlog.SetNamedFlags or mlog.SetNamedLevel, those are
+// changed for its name instead.
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/cactus/mlog"
+)
+
+// defaultName is the name of mlog.DefaultLogger.
+const defaultName = "default"
+
+// Handler is an http.Handler that shows and changes the configuration of
+// mlog.DefaultLogger and any registered Loggers.
+type Handler struct {
+	mu      sync.RWMutex
+	loggers map[string]*mlog.Logger
+}
+
+// NewHandler creates a new Handler.
+func NewHandler() *Handler {
+	return &Handler{loggers: make(map[string]*mlog.Logger)}
+}
+
+// Register adds logger to the Handler, with the given name. The name
+// "default" refers to mlog.DefaultLogger, and cannot be registered.
+func (h *Handler) Register(name string, logger *mlog.Logger) error {
+	if name == defaultName {
+		return fmt.Errorf("admin: logger name %q is reserved", name)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.loggers[name] = logger
+	return nil
+}
+
+// Unregister removes the Logger registered with name.
+func (h *Handler) Unregister(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.loggers, name)
+}
+
+// loggerInfo is the json representation of a Logger configuration.
+type loggerInfo struct {
+	Flags   string `json:"flags"`
+	Level   string `json:"level"`
+	Emitter string `json:"emitter"`
+}
+
+func newLoggerInfo(logger *mlog.Logger) loggerInfo {
+	return loggerInfo{
+		Flags:   logger.Flags().String(),
+		Level:   logger.Level().String(),
+		Emitter: strings.TrimPrefix(fmt.Sprintf("%T", logger.Emitter()), "*mlog."),
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		h.serveGet(w)
+	case http.MethodPut, http.MethodPost:
+		h.serveUpdate(w, r)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) serveGet(w http.ResponseWriter) {
+	h.mu.RLock()
+	infos := make(map[string]loggerInfo, len(h.loggers)+1)
+	for name, logger := range h.loggers {
+		infos[name] = newLoggerInfo(logger)
+	}
+	h.mu.RUnlock()
+	infos[defaultName] = newLoggerInfo(mlog.DefaultLogger)
+
+	writeJSON(w, infos)
+}
+
+func (h *Handler) serveUpdate(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	name := r.Form.Get("logger")
+	if name == "" {
+		name = defaultName
+	}
+	logger := h.logger(name)
+	if logger == nil {
+		http.Error(w, fmt.Sprintf("unknown logger %q", name), http.StatusNotFound)
+		return
+	}
+
+	// validate everything before changing anything
+	var (
+		replace         *mlog.FlagSet
+		enable, disable mlog.FlagSet
+	)
+	if r.Form.Has("flags") {
+		f, err := mlog.ParseFlagSet(r.Form.Get("flags"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		replace = &f
+	}
+	for _, v := range r.Form["enable"] {
+		f, err := mlog.ParseFlagSet(v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		enable |= f
+	}
+	for _, v := range r.Form["disable"] {
+		f, err := mlog.ParseFlagSet(v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		disable |= f
+	}
+
+	var level *mlog.Level
+	if v := r.Form.Get("level"); v != "" {
+		l, err := mlog.ParseLevel(v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		level = &l
+	}
+
+	var emitter mlog.Emitter
+	if v := r.Form.Get("emitter"); v != "" {
+		emitter = newEmitter(v)
+		if emitter == nil {
+			http.Error(w, fmt.Sprintf("unknown emitter %q", v), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// the Update methods change the flags and level shown for the Logger,
+	// which for a named Logger can be the ones set with SetNamedFlags and
+	// SetNamedLevel
+	if replace != nil || enable != 0 || disable != 0 {
+		logger.UpdateFlags(func(flags mlog.FlagSet) mlog.FlagSet {
+			if replace != nil {
+				flags = *replace
+			}
+			return (flags | enable) &^ disable
+		})
+	}
+	if level != nil {
+		logger.UpdateLevel(func(mlog.Level) mlog.Level { return *level })
+	}
+	if emitter != nil {
+		logger.SetEmitter(emitter)
+	}
+
+	writeJSON(w, map[string]loggerInfo{name: newLoggerInfo(logger)})
+}
+
+// logger returns the Logger registered with name, or nil.
+func (h *Handler) logger(name string) *mlog.Logger {
+	if name == defaultName {
+		return mlog.DefaultLogger
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.loggers[name]
+}
+
+// newEmitter returns a new Emitter of the named type, or nil.
+func newEmitter(name string) mlog.Emitter {
+	switch strings.ToLower(name) {
+	case "structured", "formatwriterstructured":
+		return &mlog.FormatWriterStructured{}
+	case "json", "formatwriterjson":
+		return &mlog.FormatWriterJSON{}
+	case "plain", "formatwriterplain":
+		return &mlog.FormatWriterPlain{}
+	default:
+		return nil
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(b, '\n'))
+}
@@ -0,0 +1,142 @@
+package admin
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cactus/mlog"
+	"github.com/dropwhile/assert"
+)
+
+func doRequest(t *testing.T, h http.Handler, method, target string, body string) (int, string) {
+	t.Helper()
+	var r *http.Request
+	if body != "" {
+		r = httptest.NewRequest(method, target, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		r = httptest.NewRequest(method, target, nil)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	b, err := io.ReadAll(w.Result().Body)
+	assert.Nil(t, err)
+	return w.Code, string(b)
+}
+
+func withDefaultLogger(t *testing.T, logger *mlog.Logger) {
+	t.Helper()
+	prev := mlog.DefaultLogger
+	mlog.DefaultLogger = logger
+	t.Cleanup(func() { mlog.DefaultLogger = prev })
+}
+
+func TestHandlerGet(t *testing.T) {
+	withDefaultLogger(t, mlog.New(io.Discard, mlog.Lstd))
+	h := NewHandler()
+	assert.Nil(t, h.Register("db", mlog.NewFormatLogger(io.Discard, mlog.Llevel, &mlog.FormatWriterJSON{})))
+	assert.NotNil(t, h.Register("default", mlog.New(io.Discard, 0)))
+
+	code, body := doRequest(t, h, "GET", "/", "")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, body, `{"db":{"flags":"FlagSet(Llevel)","level":"INFO","emitter":"FormatWriterJSON"},`+
+		`"default":{"flags":"FlagSet(Llevel|Lsort|Ltimestamp)","level":"INFO","emitter":"FormatWriterStructured"}}`+"\n")
+
+	h.Unregister("db")
+	_, body = doRequest(t, h, "GET", "/", "")
+	assert.False(t, strings.Contains(body, `"db"`))
+
+	code, _ = doRequest(t, h, "DELETE", "/", "")
+	assert.Equal(t, code, http.StatusMethodNotAllowed)
+}
+
+func TestHandlerUpdate(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := mlog.New(buf, mlog.Llevel)
+	withDefaultLogger(t, logger)
+	h := NewHandler()
+
+	logger.Debug("test")
+	assert.Equal(t, buf.String(), "")
+
+	code, body := doRequest(t, h, "PUT", "/?enable=Ldebug&emitter=json", "")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, body, `{"default":{"flags":"FlagSet(Ldebug|Llevel)","level":"INFO","emitter":"FormatWriterJSON"}}`+"\n")
+	logger.Debug("test")
+	assert.Equal(t, buf.String(), `{"level": "D", "msg": "test"}`+"\n")
+
+	code, body = doRequest(t, h, "POST", "/", "disable=Ldebug&level=error&emitter=plain")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, body, `{"default":{"flags":"FlagSet(Llevel)","level":"ERROR","emitter":"FormatWriterPlain"}}`+"\n")
+
+	code, body = doRequest(t, h, "PUT", "/?flags=FlagSet(Lshortfile|Ltimestamp)", "")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, body, `{"default":{"flags":"FlagSet(Lshortfile|Ltimestamp)","level":"ERROR","emitter":"FormatWriterPlain"}}`+"\n")
+
+	code, _ = doRequest(t, h, "PUT", "/?flags=", "")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, logger.Flags(), mlog.FlagSet(0))
+}
+
+func TestHandlerUpdateRegistered(t *testing.T) {
+	withDefaultLogger(t, mlog.New(io.Discard, 0))
+	logger := mlog.New(io.Discard, 0)
+	h := NewHandler()
+	assert.Nil(t, h.Register("db", logger))
+
+	code, _ := doRequest(t, h, "PUT", "/?logger=db&enable=Ldebug,Lshortfile", "")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, logger.Flags(), mlog.Ldebug|mlog.Lshortfile)
+	assert.Equal(t, mlog.DefaultLogger.Flags(), mlog.FlagSet(0))
+
+	code, _ = doRequest(t, h, "PUT", "/?logger=cache&enable=Ldebug", "")
+	assert.Equal(t, code, http.StatusNotFound)
+}
+
+func TestHandlerUpdateNamed(t *testing.T) {
+	withDefaultLogger(t, mlog.New(io.Discard, 0))
+	defer mlog.ResetNamed("admintest")
+	defer mlog.ResetNamed("admintest.pool")
+	logger := mlog.New(io.Discard, 0).Named("admintest.pool")
+	h := NewHandler()
+	assert.Nil(t, h.Register("pool", logger))
+
+	// without named settings, the Logger's own flags are changed
+	code, _ := doRequest(t, h, "PUT", "/?logger=pool&enable=Lshortfile", "")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, logger.Flags(), mlog.Lshortfile)
+
+	// with named settings, they are changed for the Logger name
+	mlog.SetNamedFlags("admintest", mlog.Llevel)
+	mlog.SetNamedLevel("admintest", mlog.LevelWarn)
+	code, body := doRequest(t, h, "PUT", "/?logger=pool&enable=Ldebug&level=error", "")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, body, `{"pool":{"flags":"FlagSet(Ldebug|Llevel)","level":"ERROR","emitter":"FormatWriterStructured"}}`+"\n")
+	assert.Equal(t, logger.Flags(), mlog.Ldebug|mlog.Llevel)
+	assert.Equal(t, logger.Level(), mlog.LevelError)
+	// the prefix settings are unchanged
+	assert.Equal(t, mlog.New(io.Discard, 0).Named("admintest").Flags(), mlog.Llevel)
+}
+
+func TestHandlerUpdateInvalid(t *testing.T) {
+	logger := mlog.New(io.Discard, mlog.Llevel)
+	withDefaultLogger(t, logger)
+	h := NewHandler()
+
+	for _, query := range []string{
+		"enable=Lnope",
+		"disable=Ldebug|Lnope",
+		"enable=Ldebug&level=loud",
+		"enable=Ldebug&emitter=xml",
+	} {
+		code, _ := doRequest(t, h, "PUT", "/?"+query, "")
+		assert.Equal(t, code, http.StatusBadRequest, query)
+	}
+	// nothing was changed by the invalid requests
+	assert.Equal(t, logger.Flags(), mlog.Llevel)
+	assert.Equal(t, logger.Level(), mlog.LevelInfo)
+}
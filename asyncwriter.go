@@ -0,0 +1,240 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mlog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrWriterClosed is returned when writing to an AsyncWriter that has been
+// closed.
+var ErrWriterClosed = errors.New("mlog: write to closed writer")
+
+// OverflowPolicy determines what an AsyncWriter does with a line written
+// while its queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits for space in the queue.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest discards the line being written.
+	OverflowDropNewest
+	// OverflowDropOldest discards the oldest queued line, to make space for
+	// the line being written.
+	OverflowDropOldest
+)
+
+// AsyncOptions configures an AsyncWriter.
+type AsyncOptions struct {
+	// QueueSize is the maximum number of lines queued. Defaults to 1024.
+	QueueSize int
+	// Overflow is the policy for lines written while the queue is full.
+	Overflow OverflowPolicy
+	// ReportInterval is how often a "dropped N messages" record is written,
+	// if any lines were dropped. Defaults to 10 seconds.
+	ReportInterval time.Duration
+	// Emitter and Flags are used to format the "dropped N messages" record.
+	// Emitter defaults to FormatWriterStructured.
+	Emitter Emitter
+	Flags   FlagSet
+}
+
+// AsyncWriter is an io.Writer that queues lines, and writes them to an
+// underlying io.Writer from a background goroutine, so that a slow output
+// does not stall the goroutines that are logging.
+//
+// Each Write is queued as a single line, so AsyncWriter should be used as the
+// output of a Logger, and not shared with other writers.
+type AsyncWriter struct {
+	out      io.Writer
+	queue    chan *sliceBuffer
+	overflow OverflowPolicy
+	interval time.Duration
+	reporter *Logger
+
+	dropped  atomic.Uint64
+	reported uint64 // only accessed by the run goroutine
+
+	// mu is held for reading by Write, and for writing by Close, so that no
+	// line is queued after the run goroutine has stopped
+	mu     sync.RWMutex
+	closed bool
+
+	flush   chan chan error
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// NewAsyncWriter creates a new AsyncWriter that writes to out, and starts its
+// background goroutine. Call Close to stop it.
+func NewAsyncWriter(out io.Writer, opts AsyncOptions) *AsyncWriter {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1024
+	}
+	if opts.ReportInterval <= 0 {
+		opts.ReportInterval = 10 * time.Second
+	}
+	if opts.Emitter == nil {
+		opts.Emitter = &FormatWriterStructured{}
+	}
+
+	w := &AsyncWriter{
+		out:      out,
+		queue:    make(chan *sliceBuffer, opts.QueueSize),
+		overflow: opts.Overflow,
+		interval: opts.ReportInterval,
+		// the report is written directly to out by the run goroutine, so it
+		// is not queued behind (or dropped with) the other lines
+		reporter: NewFormatLogger(out, opts.Flags, opts.Emitter),
+		flush:    make(chan chan error),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write queues a copy of b to be written to the underlying io.Writer. If the
+// queue is full, the OverflowPolicy is applied. Write does not return errors
+// from the underlying io.Writer.
+func (w *AsyncWriter) Write(b []byte) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return 0, ErrWriterClosed
+	}
+
+	buf := bufPool.Get()
+	buf.Write(b)
+
+	switch w.overflow {
+	case OverflowDropNewest:
+		select {
+		case w.queue <- buf:
+		default:
+			bufPool.Put(buf)
+			w.dropped.Add(1)
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case w.queue <- buf:
+				return len(b), nil
+			default:
+			}
+			select {
+			case old := <-w.queue:
+				bufPool.Put(old)
+				w.dropped.Add(1)
+			default:
+			}
+		}
+	default:
+		// the run goroutine keeps draining the queue until Close has
+		// acquired mu, so this cannot block forever
+		w.queue <- buf
+	}
+	return len(b), nil
+}
+
+// Dropped returns the number of lines dropped so far.
+func (w *AsyncWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Flush waits until all lines queued before the call are written. If the
+// underlying io.Writer has a Flush method, it is then called as well.
+func (w *AsyncWriter) Flush() error {
+	req := make(chan error, 1)
+	select {
+	case w.flush <- req:
+		return <-req
+	case <-w.stopped:
+		return nil
+	}
+}
+
+// Close writes any queued lines, and stops the background goroutine.
+// Writes after Close return ErrWriterClosed.
+func (w *AsyncWriter) Close() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.done)
+	}
+	w.mu.Unlock()
+	<-w.stopped
+	return nil
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.stopped)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case buf := <-w.queue:
+			w.write(buf)
+		case <-ticker.C:
+			w.report()
+		case req := <-w.flush:
+			w.drain()
+			w.report()
+			req <- flushWriter(w.out)
+		case <-w.done:
+			w.drain()
+			w.report()
+			return
+		}
+	}
+}
+
+// drain writes all currently queued lines.
+func (w *AsyncWriter) drain() {
+	for {
+		select {
+		case buf := <-w.queue:
+			w.write(buf)
+		default:
+			return
+		}
+	}
+}
+
+func (w *AsyncWriter) write(buf *sliceBuffer) {
+	// errors are ignored; there is nowhere to report them
+	_, _ = w.out.Write(buf.Bytes())
+	bufPool.Put(buf)
+}
+
+// report writes a record with the number of lines dropped since the last
+// report, if any.
+func (w *AsyncWriter) report() {
+	dropped := w.dropped.Load()
+	if dropped == w.reported {
+		return
+	}
+	n := dropped - w.reported
+	w.reported = dropped
+	w.reporter.Infox(
+		fmt.Sprintf("dropped %d messages", n),
+		Uint64("dropped", n),
+	)
+}
+
+// flushWriter calls the Flush method of w, if it has one.
+func flushWriter(w io.Writer) error {
+	if f, ok := w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package mlog
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dropwhile/assert"
+)
+
+// blockingWriter blocks each Write until release is closed.
+type blockingWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func newBlockingWriter() *blockingWriter {
+	return &blockingWriter{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+}
+
+func (bw *blockingWriter) Write(b []byte) (int, error) {
+	bw.once.Do(func() { close(bw.started) })
+	<-bw.release
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+	return bw.buf.Write(b)
+}
+
+func (bw *blockingWriter) String() string {
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+	return bw.buf.String()
+}
+
+func TestAsyncWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewAsyncWriter(buf, AsyncOptions{})
+	logger := New(w, 0)
+	for i := 0; i < 100; i++ {
+		logger.Infox("test", Int("i", i))
+	}
+	assert.Nil(t, logger.Flush())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, 100, len(lines), "unexpected number of lines")
+	assert.Equal(t, `msg="test" i="0"`, lines[0])
+	assert.Equal(t, `msg="test" i="99"`, lines[99])
+	assert.Equal(t, uint64(0), w.Dropped())
+
+	assert.Nil(t, w.Close())
+	_, err := w.Write([]byte("after close\n"))
+	assert.Error(t, err, ErrWriterClosed)
+	// Flush and Close after Close are no-ops
+	assert.Nil(t, w.Flush())
+	assert.Nil(t, w.Close())
+}
+
+func TestAsyncWriterOverflow(t *testing.T) {
+	var tests = []struct {
+		overflow OverflowPolicy
+		expected string
+	}{
+		{OverflowDropNewest, "first\nsecond\nthird\n"},
+		{OverflowDropOldest, "first\nfourth\nfifth\n"},
+	}
+
+	for _, tt := range tests {
+		bw := newBlockingWriter()
+		w := NewAsyncWriter(bw, AsyncOptions{QueueSize: 2, Overflow: tt.overflow})
+		w.Write([]byte("first\n"))
+		// wait for the run goroutine to block writing "first"
+		<-bw.started
+		for _, s := range []string{"second", "third", "fourth", "fifth"} {
+			w.Write([]byte(s + "\n"))
+		}
+		assert.Equal(t, uint64(2), w.Dropped())
+
+		close(bw.release)
+		assert.Nil(t, w.Close())
+		assert.Equal(t, tt.expected+`msg="dropped 2 messages" dropped="2"`+"\n", bw.String())
+	}
+}
+
+func TestAsyncWriterBlock(t *testing.T) {
+	bw := newBlockingWriter()
+	w := NewAsyncWriter(bw, AsyncOptions{QueueSize: 1})
+	w.Write([]byte("first\n"))
+	<-bw.started
+	w.Write([]byte("second\n"))
+
+	written := make(chan struct{})
+	go func() {
+		w.Write([]byte("third\n"))
+		close(written)
+	}()
+
+	select {
+	case <-written:
+		t.Fatal("write did not block")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(bw.release)
+	<-written
+	assert.Nil(t, w.Close())
+	assert.Equal(t, "first\nsecond\nthird\n", bw.String())
+	assert.Equal(t, uint64(0), w.Dropped())
+}
+
+func TestAsyncWriterReportInterval(t *testing.T) {
+	bw := newBlockingWriter()
+	w := NewAsyncWriter(bw, AsyncOptions{
+		QueueSize:      1,
+		Overflow:       OverflowDropNewest,
+		ReportInterval: 10 * time.Millisecond,
+		Emitter:        &FormatWriterJSON{},
+	})
+	defer w.Close()
+
+	w.Write([]byte("first\n"))
+	<-bw.started
+	w.Write([]byte("second\n"))
+	w.Write([]byte("third\n"))
+	close(bw.release)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(bw.String(), "dropped 1 messages") {
+		if time.Now().After(deadline) {
+			t.Fatalf("no dropped report written: %q", bw.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Equal(t,
+		"first\nsecond\n"+`{"msg": "dropped 1 messages", "extra": {"dropped": 1}}`+"\n",
+		bw.String(),
+	)
+}
+
+func TestAsyncWriterCloseConcurrent(t *testing.T) {
+	buf := &syncBuffer{}
+	w := NewAsyncWriter(buf, AsyncOptions{QueueSize: 4})
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	written := 0
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if _, err := w.Write([]byte("line\n")); err != nil {
+					assert.Error(t, err, ErrWriterClosed)
+					return
+				}
+				mu.Lock()
+				written++
+				mu.Unlock()
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	assert.Nil(t, w.Close())
+	wg.Wait()
+
+	// every accepted line is written before Close returns
+	assert.Equal(t, written, strings.Count(buf.String(), "line\n"))
+}
+
+// flushRecorder records calls to its Flush method.
+type flushRecorder struct {
+	bytes.Buffer
+	flushed int
+}
+
+func (fr *flushRecorder) Flush() error {
+	fr.flushed++
+	return nil
+}
+
+func TestPanicFlush(t *testing.T) {
+	fr := &flushRecorder{}
+	logger := New(fr, 0)
+
+	assertPanic(t, func() { logger.Panicx("test") })
+	assertPanic(t, func() { logger.Panicm("test", nil) })
+	assertPanic(t, func() { logger.Panicf("test") })
+	assertPanic(t, func() { logger.Panic("test") })
+	assert.Equal(t, 4, fr.flushed, "expected a flush per panic")
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mlog
+
+import "runtime"
+
+// callerPC returns the program counter of the function skip frames above
+// the caller of callerPC, with the same skip semantics as runtime.Caller.
+func callerPC(skip int) uintptr {
+	var pcs [1]uintptr
+	// skip runtime.Callers and callerPC itself
+	if runtime.Callers(skip+2, pcs[:]) == 0 {
+		return 0
+	}
+	return pcs[0]
+}
+
+// callerFileLine returns the file path and line number for pc. If short is
+// true, only the final element of the file path is returned.
+func callerFileLine(pc uintptr, short bool) (string, int) {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	file, line := frame.File, frame.Line
+	if file == "" {
+		return "???", 0
+	}
+
+	if short {
+		for i := len(file) - 1; i > 0; i-- {
+			if file[i] == '/' {
+				file = file[i+1:]
+				break
+			}
+		}
+	}
+	return file, line
+}
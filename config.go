@@ -0,0 +1,322 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cactus/mlog/rotate"
+)
+
+// Config describes a Logger, and can be loaded from json, as in:
+//
+//	{
+//	    "format": "json",
+//	    "flags": "Ltimestamp|Llevel|Lsort",
+//	    "level": "info",
+//	    "fields": {"service": "billing"},
+//	    "outputs": [
+//	        {"type": "stderr"},
+//	        {"type": "file", "path": "/var/log/billing.log", "max_size": 104857600, "interval": "daily", "max_backups": 7, "compress": true},
+//	        {"type": "net", "network": "udp", "address": "127.0.0.1:5140"}
+//	    ]
+//	}
+//
+// Build or BuildCloser create the Logger described by a Config.
+type Config struct {
+	// Outputs are the destinations each log line is written to. Defaults to
+	// stderr.
+	Outputs []OutputConfig `json:"outputs,omitempty"`
+	// Format is the Emitter: structured (the default), json or plain.
+	Format string `json:"format,omitempty"`
+	// Flags are the Logger flags, as parsed by ParseFlagSet. Defaults to Lstd.
+	Flags *FlagSet `json:"flags,omitempty"`
+	// Debug sets the Ldebug flag, in addition to Flags.
+	Debug bool `json:"debug,omitempty"`
+	// Level is the minimum level: debug, info (the default), warn, error or
+	// fatal.
+	Level string `json:"level,omitempty"`
+	// Fields are static fields included in every log line.
+	Fields map[string]interface{} `json:"fields,omitempty"`
+}
+
+// OutputConfig describes a Logger output.
+type OutputConfig struct {
+	// Type is stderr, stdout, file or net.
+	Type string `json:"type"`
+
+	// Path is the file path, for the file type. The file is rotated as
+	// configured by the remaining file fields, using mlog/rotate.
+	Path string `json:"path,omitempty"`
+	// MaxSize is the size in bytes at which the file is rotated.
+	MaxSize int64 `json:"max_size,omitempty"`
+	// Interval rotates the file every interval: hourly, daily, or a duration
+	// as parsed by time.ParseDuration.
+	Interval string `json:"interval,omitempty"`
+	// MaxBackups is the number of rotated files to keep.
+	MaxBackups int `json:"max_backups,omitempty"`
+	// MaxAge is how long to keep rotated files, as parsed by
+	// time.ParseDuration.
+	MaxAge string `json:"max_age,omitempty"`
+	// Compress gzips rotated files.
+	Compress bool `json:"compress,omitempty"`
+
+	// Network and Address are the network (tcp, udp, unix or unixgram) and
+	// address to connect to, for the net type. See NetWriter.
+	Network string `json:"network,omitempty"`
+	Address string `json:"address,omitempty"`
+}
+
+// ParseConfig parses a json Config. Unknown fields are an error, so that
+// typos are not silently ignored.
+func ParseConfig(data []byte) (*Config, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	cfg := &Config{}
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("mlog: invalid config: %w", err)
+	}
+	return cfg, nil
+}
+
+// LoadConfig reads and parses the json Config file at path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(data)
+}
+
+// Build creates a new Logger as described by the Config. The outputs opened
+// by Build stay open for the life of the process; use BuildCloser to close
+// them when the Logger is no longer used. Logger.Flush flushes the outputs.
+func (cfg *Config) Build() (*Logger, error) {
+	logger, _, err := cfg.BuildCloser()
+	return logger, err
+}
+
+// BuildCloser is like Build, and also returns an io.Closer that closes the
+// outputs opened by BuildCloser (other than stdout and stderr). Call it when
+// the Logger is no longer used, such as on shutdown.
+func (cfg *Config) BuildCloser() (*Logger, io.Closer, error) {
+	s, err := cfg.settings()
+	if err != nil {
+		return nil, nil, err
+	}
+	logger, out, err := cfg.build(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	return logger, out, nil
+}
+
+// build creates a new Logger with the settings s of the Config, and returns
+// it along with its output.
+func (cfg *Config) build(s *configSettings) (*Logger, *configOutput, error) {
+	out, err := cfg.openOutputs()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	logger := NewFormatLogger(out, s.flags, s.emitter)
+	logger.SetLevel(s.level)
+	if len(s.fields) > 0 {
+		logger = logger.With(s.fields...)
+	}
+	return logger, out, nil
+}
+
+// configSettings are the validated settings of a Config, other than its
+// outputs.
+type configSettings struct {
+	format  string
+	emitter Emitter
+	flags   FlagSet
+	level   Level
+	fields  []*Attr
+}
+
+func (cfg *Config) settings() (*configSettings, error) {
+	s := &configSettings{flags: Lstd, level: LevelInfo}
+
+	s.format = strings.ToLower(cfg.Format)
+	switch s.format {
+	case "", "structured":
+		s.format = "structured"
+		s.emitter = &FormatWriterStructured{}
+	case "json":
+		s.emitter = &FormatWriterJSON{}
+	case "plain":
+		s.emitter = &FormatWriterPlain{}
+	default:
+		return nil, fmt.Errorf("mlog: unknown format %q", cfg.Format)
+	}
+
+	if cfg.Flags != nil {
+		s.flags = *cfg.Flags
+	}
+	if cfg.Debug {
+		s.flags |= Ldebug
+	}
+
+	if cfg.Level != "" {
+		level, err := ParseLevel(cfg.Level)
+		if err != nil {
+			return nil, err
+		}
+		s.level = level
+	}
+
+	keys := make([]string, 0, len(cfg.Fields))
+	for k := range cfg.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s.fields = append(s.fields, A(k, cfg.Fields[k]))
+	}
+	return s, nil
+}
+
+// outputs returns the outputs of the Config, which default to stderr.
+func (cfg *Config) outputs() []OutputConfig {
+	if len(cfg.Outputs) == 0 {
+		return []OutputConfig{{Type: "stderr"}}
+	}
+	return cfg.Outputs
+}
+
+func (cfg *Config) openOutputs() (*configOutput, error) {
+	out := &configOutput{}
+	for _, oc := range cfg.outputs() {
+		w, err := oc.open()
+		if err != nil {
+			out.Close()
+			return nil, err
+		}
+		out.outputs = append(out.outputs, oc)
+		out.writers = append(out.writers, w)
+	}
+	return out, nil
+}
+
+// filePath returns the cleaned path of a file output, or "" for other
+// outputs.
+func (oc *OutputConfig) filePath() string {
+	if strings.ToLower(oc.Type) != "file" || oc.Path == "" {
+		return ""
+	}
+	return filepath.Clean(oc.Path)
+}
+
+// open opens the output.
+func (oc *OutputConfig) open() (io.Writer, error) {
+	switch strings.ToLower(oc.Type) {
+	case "stderr":
+		return os.Stderr, nil
+	case "stdout":
+		return os.Stdout, nil
+	case "file":
+		if oc.Path == "" {
+			return nil, fmt.Errorf("mlog: file output requires a path")
+		}
+		opts := rotate.Options{
+			MaxSize:    oc.MaxSize,
+			MaxBackups: oc.MaxBackups,
+			Compress:   oc.Compress,
+		}
+		switch strings.ToLower(oc.Interval) {
+		case "":
+		case "hourly":
+			opts.Interval = rotate.Hourly
+		case "daily":
+			opts.Interval = rotate.Daily
+		default:
+			d, err := time.ParseDuration(oc.Interval)
+			if err != nil {
+				return nil, fmt.Errorf("mlog: invalid rotation interval %q", oc.Interval)
+			}
+			opts.Interval = d
+		}
+		if oc.MaxAge != "" {
+			d, err := time.ParseDuration(oc.MaxAge)
+			if err != nil {
+				return nil, fmt.Errorf("mlog: invalid max age %q", oc.MaxAge)
+			}
+			opts.MaxAge = d
+		}
+		return rotate.New(oc.Path, opts)
+	case "net":
+		if oc.Network == "" || oc.Address == "" {
+			return nil, fmt.Errorf("mlog: net output requires a network and address")
+		}
+		return NewNetWriter(oc.Network, oc.Address)
+	default:
+		return nil, fmt.Errorf("mlog: unknown output type %q", oc.Type)
+	}
+}
+
+// configOutput is the output of a Logger built from a Config. It writes to
+// each of its writers.
+type configOutput struct {
+	outputs []OutputConfig // the config of each writer
+	writers []io.Writer
+}
+
+func (o *configOutput) Write(p []byte) (int, error) {
+	var firstErr error
+	for _, w := range o.writers {
+		if _, err := w.Write(p); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return 0, firstErr
+	}
+	return len(p), nil
+}
+
+// Flush flushes each writer that has a Flush method.
+func (o *configOutput) Flush() error {
+	var firstErr error
+	for _, w := range o.writers {
+		if err := flushWriter(w); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Close closes the writers opened for the output. Stdout and stderr are not
+// closed.
+func (o *configOutput) Close() error {
+	var firstErr error
+	for _, w := range o.writers {
+		if err := closeWriter(w); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// closeWriter closes w, if it is an io.Closer other than stdout or stderr.
+func closeWriter(w io.Writer) error {
+	if w == os.Stderr || w == os.Stdout {
+		return nil
+	}
+	if c, ok := w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package mlog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dropwhile/assert"
+)
+
+func TestConfigBuild(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	cfg, err := ParseConfig([]byte(`{
+		"format": "json",
+		"flags": "Llevel|Lsort",
+		"level": "warn",
+		"fields": {"service": "billing", "replica": 2},
+		"outputs": [
+			{"type": "file", "path": "` + path + `", "max_size": 1048576, "interval": "daily", "max_age": "72h"}
+		]
+	}`))
+	assert.Nil(t, err)
+
+	logger, closer, err := cfg.BuildCloser()
+	assert.Nil(t, err)
+	assert.Equal(t, logger.Flags(), Llevel|Lsort)
+	assert.Equal(t, logger.Level(), LevelWarn)
+	_, ok := logger.Emitter().(*FormatWriterJSON)
+	assert.True(t, ok)
+
+	logger.Info("hidden")
+	logger.Warnx("test", Int("a", 1))
+
+	b, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, string(b), `{"level": "W", "msg": "test", "extra": {"replica": 2, "service": "billing", "a": 1}}`+"\n")
+
+	// the file output is closed by the returned io.Closer
+	assert.Nil(t, logger.Flush())
+	assert.Nil(t, closer.Close())
+	logger.Warn("closed")
+	b, err = os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Count(string(b), "\n"), 1)
+}
+
+func TestConfigBuildDefaults(t *testing.T) {
+	logger, closer, err := (&Config{}).BuildCloser()
+	assert.Nil(t, err)
+	// stderr is not closed
+	assert.Nil(t, closer.Close())
+	assert.Equal(t, logger.Flags(), Lstd)
+	assert.Equal(t, logger.Level(), LevelInfo)
+	_, ok := logger.Emitter().(*FormatWriterStructured)
+	assert.True(t, ok)
+
+	logger, err = (&Config{Debug: true, Outputs: []OutputConfig{{Type: "stdout"}}}).Build()
+	assert.Nil(t, err)
+	assert.Equal(t, logger.Flags(), Lstd|Ldebug)
+}
+
+func TestConfigInvalid(t *testing.T) {
+	_, err := ParseConfig([]byte(`{"formats": "json"}`))
+	assert.NotNil(t, err)
+	_, err = ParseConfig([]byte(`{"flags": "Lnope"}`))
+	assert.NotNil(t, err)
+
+	var tests = []struct {
+		cfg  Config
+		want string
+	}{
+		{Config{Format: "xml"}, `mlog: unknown format "xml"`},
+		{Config{Level: "loud"}, `mlog: unknown level "loud"`},
+		{Config{Outputs: []OutputConfig{{Type: "pipe"}}}, `mlog: unknown output type "pipe"`},
+		{Config{Outputs: []OutputConfig{{Type: "file"}}}, `mlog: file output requires a path`},
+		{Config{Outputs: []OutputConfig{{Type: "file", Path: "x.log", Interval: "weekly"}}}, `mlog: invalid rotation interval "weekly"`},
+		{Config{Outputs: []OutputConfig{{Type: "net", Network: "tcp"}}}, `mlog: net output requires a network and address`},
+	}
+
+	for _, tc := range tests {
+		_, err := tc.cfg.Build()
+		assert.Equal(t, err.Error(), tc.want)
+	}
+}
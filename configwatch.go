@@ -0,0 +1,323 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mlog
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ConfigWatcher is a Logger built from a Config file, that is reconfigured
+// when the file changes. Changes to the outputs, format, flags and level are
+// applied to the Logger (and all Loggers sharing its output, see
+// Logger.With) without losing or interleaving log lines. Changes to Fields
+// only apply to Loggers created by a new ConfigWatcher.
+//
+// Only the settings that changed in the file are applied, so that changes
+// made at runtime (as with SetLevel, SetFlags or EnableDebugFor) are kept
+// until the file changes the same setting. Outputs that did not change keep
+// writing through the same writer.
+//
+// If the changed file cannot be loaded, or describes an invalid Config, an
+// error record is logged and the previous configuration is kept.
+type ConfigWatcher struct {
+	path     string
+	interval time.Duration
+	logger   *Logger
+	done     chan struct{}
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	out      *configOutput
+	settings *configSettings // last applied
+	modTime  time.Time
+	size     int64
+	closed   bool
+}
+
+// WatchConfig builds a Logger from the Config file at path, and polls the
+// file for changes (of its modification time or size) every interval, as
+// in:
+//
+//	w, err := mlog.WatchConfig("/etc/billing/log.json", 5*time.Second)
+//	if err != nil {
+//	    // handle error
+//	}
+//	defer w.Close()
+//	logger := w.Logger()
+//
+// Polling is used so that no file notification mechanism is required.
+func WatchConfig(path string, interval time.Duration) (*ConfigWatcher, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := cfg.settings()
+	if err != nil {
+		return nil, err
+	}
+	logger, out, err := cfg.build(s)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &ConfigWatcher{
+		path:     path,
+		interval: interval,
+		logger:   logger,
+		done:     make(chan struct{}),
+		out:      out,
+		settings: s,
+		modTime:  fi.ModTime(),
+		size:     fi.Size(),
+	}
+	w.wg.Add(1)
+	go w.run()
+	return w, nil
+}
+
+// Logger returns the Logger configured by the watched file.
+func (w *ConfigWatcher) Logger() *Logger {
+	return w.logger
+}
+
+func (w *ConfigWatcher) run() {
+	defer w.wg.Done()
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.poll()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// poll reloads the file if it changed since it was last loaded.
+func (w *ConfigWatcher) poll() {
+	fi, err := os.Stat(w.path)
+	if err != nil {
+		// the file may be in the middle of being replaced, so only report
+		// errors when reloading
+		return
+	}
+
+	w.mu.Lock()
+	changed := !fi.ModTime().Equal(w.modTime) || fi.Size() != w.size
+	w.modTime, w.size = fi.ModTime(), fi.Size()
+	w.mu.Unlock()
+
+	if changed {
+		_ = w.Reload()
+	}
+}
+
+// Reload reloads the watched file and applies it to the Logger. On error, an
+// error record is logged, and the previous configuration is kept. Reload
+// returns ErrWriterClosed after Close.
+func (w *ConfigWatcher) Reload() error {
+	err := w.reload()
+	if errors.Is(err, ErrWriterClosed) {
+		return err
+	}
+	if err != nil {
+		w.logger.emitAttrs(LevelError, "config reload failed",
+			String("path", w.path), Err("error", err))
+		return err
+	}
+	w.logger.emitAttrs(LevelInfo, "config reloaded", String("path", w.path))
+	return nil
+}
+
+func (w *ConfigWatcher) reload() error {
+	w.mu.Lock()
+	closed := w.closed
+	w.mu.Unlock()
+	if closed {
+		return ErrWriterClosed
+	}
+
+	cfg, err := LoadConfig(w.path)
+	if err != nil {
+		return err
+	}
+	s, err := cfg.settings()
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrWriterClosed
+	}
+
+	prev, ps := w.out, w.settings
+	out := &configOutput{outputs: cfg.outputs()}
+	out.writers = make([]io.Writer, len(out.outputs))
+
+	// reuse the writers of unchanged outputs; the other writers are stale
+	stale := make([]bool, len(prev.writers))
+	for j := range stale {
+		stale[j] = true
+	}
+	for i, oc := range out.outputs {
+		for j := range prev.outputs {
+			if stale[j] && prev.outputs[j] == oc {
+				out.writers[i], stale[j] = prev.writers[j], false
+				break
+			}
+		}
+	}
+
+	// open the new outputs, other than files still open by a stale writer:
+	// two rotate Writers must not write to (and rotate) the same file, so
+	// these are only opened once the stale writer is closed
+	held := map[string]bool{}
+	for j, oc := range prev.outputs {
+		if p := oc.filePath(); stale[j] && p != "" {
+			held[p] = true
+		}
+	}
+	var opened []io.Writer
+	var replace []int
+	for i, oc := range out.outputs {
+		if out.writers[i] != nil {
+			continue
+		}
+		if held[oc.filePath()] {
+			replace = append(replace, i)
+			continue
+		}
+		wr, err := oc.open()
+		if err != nil {
+			closeWriters(opened)
+			return err
+		}
+		out.writers[i] = wr
+		opened = append(opened, wr)
+	}
+
+	// swap everything while holding the output lock, so that no line is
+	// written to the previous output once the new one is in place
+	l := w.logger
+	l.mu.Lock()
+	if err := w.replaceFiles(prev, out, stale, replace, &opened); err != nil {
+		l.mu.Unlock()
+		closeWriters(opened)
+		return err
+	}
+	l.out = out
+	l.update(func(st *coreState) { s.apply(st, ps) })
+	l.mu.Unlock()
+
+	w.out, w.settings = out, s
+	var firstErr error
+	for j, wr := range prev.writers {
+		if !stale[j] {
+			continue
+		}
+		_ = flushWriter(wr)
+		if err := closeWriter(wr); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// replaceFiles opens the outputs of out at the indexes replace, which are
+// files open by stale writers of prev. The stale writers of these files are
+// closed (and no longer stale) first. If an output cannot be opened, the
+// closed writers are reopened and the Logger output is restored to them. It
+// must be called with the Logger output lock held.
+func (w *ConfigWatcher) replaceFiles(prev, out *configOutput, stale []bool, replace []int, opened *[]io.Writer) error {
+	if len(replace) == 0 {
+		return nil
+	}
+	paths := map[string]bool{}
+	for _, i := range replace {
+		paths[out.outputs[i].filePath()] = true
+	}
+	var closed []int
+	for j, oc := range prev.outputs {
+		if stale[j] && paths[oc.filePath()] {
+			_ = flushWriter(prev.writers[j])
+			_ = closeWriter(prev.writers[j])
+			stale[j] = false
+			closed = append(closed, j)
+		}
+	}
+
+	for _, i := range replace {
+		wr, err := out.outputs[i].open()
+		if err != nil {
+			// restore the previous output, keeping it unchanged for any
+			// concurrent Flush
+			restored := &configOutput{outputs: prev.outputs}
+			restored.writers = append([]io.Writer(nil), prev.writers...)
+			for _, j := range closed {
+				if wr, err := prev.outputs[j].open(); err == nil {
+					restored.writers[j] = wr
+				}
+			}
+			w.logger.out = restored
+			w.out = restored
+			return err
+		}
+		out.writers[i] = wr
+		*opened = append(*opened, wr)
+	}
+	return nil
+}
+
+// apply applies the settings of s that differ from the previously applied
+// settings prev to st, so that changes made at runtime are kept unless s
+// changes the same setting.
+func (s *configSettings) apply(st *coreState, prev *configSettings) {
+	if s.format != prev.format {
+		st.e = s.emitter
+	}
+	changed := s.flags ^ prev.flags
+	st.flags = st.flags&^changed | s.flags&changed
+	if s.level != prev.level {
+		st.level = s.level
+	}
+}
+
+// closeWriters closes each of writers.
+func closeWriters(writers []io.Writer) {
+	for _, w := range writers {
+		_ = closeWriter(w)
+	}
+}
+
+// Close stops watching the file, and closes the outputs of the Logger.
+func (w *ConfigWatcher) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	close(w.done)
+	w.wg.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_ = w.out.Flush()
+	return w.out.Close()
+}
@@ -0,0 +1,162 @@
+package mlog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dropwhile/assert"
+)
+
+func writeConfigFile(t *testing.T, path, data string) {
+	t.Helper()
+	assert.Nil(t, os.WriteFile(path, []byte(data), 0o644))
+}
+
+func readLogFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	return string(b)
+}
+
+func TestConfigWatcherReload(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "log.json")
+	log1 := filepath.Join(dir, "one.log")
+	log2 := filepath.Join(dir, "two.log")
+
+	writeConfigFile(t, cfgPath, `{"flags": "", "outputs": [{"type": "file", "path": "`+log1+`"}]}`)
+	w, err := WatchConfig(cfgPath, time.Hour)
+	assert.Nil(t, err)
+	defer w.Close()
+
+	logger := w.Logger().With(String("a", "b"))
+	logger.Info("one")
+
+	writeConfigFile(t, cfgPath, `{"format": "json", "flags": "Llevel", "level": "warn",
+		"outputs": [{"type": "file", "path": "`+log2+`"}]}`)
+	assert.Nil(t, w.Reload())
+	logger.Info("hidden")
+	logger.Warn("two")
+
+	assert.Equal(t, readLogFile(t, log1), `msg="one" a="b"`+"\n")
+	assert.Equal(t, readLogFile(t, log2), strings.Join([]string{
+		`{"level": "I", "msg": "config reloaded", "extra": {"path": "` + cfgPath + `"}}`,
+		`{"level": "W", "msg": "two", "extra": {"a": "b"}}`,
+	}, "\n")+"\n")
+
+	// an invalid config is rejected, and the previous config is kept
+	writeConfigFile(t, cfgPath, `{"format": "xml"}`)
+	err = w.Reload()
+	assert.Equal(t, err.Error(), `mlog: unknown format "xml"`)
+	logger.Warn("three")
+	assert.Equal(t, logger.Flags(), Llevel)
+	assert.True(t, strings.HasSuffix(readLogFile(t, log2), strings.Join([]string{
+		`{"level": "E", "msg": "config reload failed", "extra": {"path": "` + cfgPath + `", "error": "mlog: unknown format \"xml\""}}`,
+		`{"level": "W", "msg": "three", "extra": {"a": "b"}}`,
+	}, "\n")+"\n"))
+
+	assert.Nil(t, w.Close())
+	assert.Error(t, w.Reload(), ErrWriterClosed)
+}
+
+func TestConfigWatcherReloadSamePath(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "log.json")
+	logPath := filepath.Join(dir, "app.log")
+
+	writeConfigFile(t, cfgPath, `{"flags": "", "outputs": [{"type": "stdout"}, {"type": "file", "path": "`+logPath+`"}]}`)
+	w, err := WatchConfig(cfgPath, time.Hour)
+	assert.Nil(t, err)
+	defer w.Close()
+	stdout, file := w.out.writers[0], w.out.writers[1]
+
+	// a changed output with the same path replaces the previous writer,
+	// which is closed first
+	writeConfigFile(t, cfgPath, `{"flags": "", "outputs": [{"type": "stdout"}, {"type": "file", "path": "`+logPath+`", "max_size": 1048576}]}`)
+	assert.Nil(t, w.Reload())
+	assert.Equal(t, w.out.writers[0], stdout)
+	assert.NotEqual(t, w.out.writers[1], file)
+	_, err = file.Write([]byte("x\n"))
+	assert.Error(t, err, os.ErrClosed)
+
+	w.Logger().Info("one")
+	assert.Equal(t, readLogFile(t, logPath), strings.Join([]string{
+		`msg="config reloaded" path="` + cfgPath + `"`,
+		`msg="one"`,
+	}, "\n")+"\n")
+
+	// an output that cannot be opened restores the previous output
+	writeConfigFile(t, cfgPath, `{"flags": "", "outputs": [{"type": "file", "path": "`+logPath+`", "interval": "often"}]}`)
+	assert.NotNil(t, w.Reload())
+	assert.Equal(t, len(w.out.writers), 2)
+	assert.Equal(t, w.out.outputs[1].MaxSize, 1048576)
+	w.Logger().Info("two")
+	assert.True(t, strings.HasSuffix(readLogFile(t, logPath), `msg="two"`+"\n"))
+}
+
+func TestConfigWatcherReloadKeepsOverrides(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "log.json")
+	logPath := filepath.Join(dir, "app.log")
+
+	writeConfigFile(t, cfgPath, `{"flags": "Llevel", "outputs": [{"type": "file", "path": "`+logPath+`"}]}`)
+	w, err := WatchConfig(cfgPath, time.Hour)
+	assert.Nil(t, err)
+	defer w.Close()
+
+	logger := w.Logger()
+	logger.SetLevel(LevelWarn)
+	logger.EnableDebugFor(time.Hour)
+
+	// settings unchanged by the file keep their runtime values
+	writeConfigFile(t, cfgPath, `{"flags": "Llevel|Lsort", "outputs": [{"type": "file", "path": "`+logPath+`"}]}`)
+	assert.Nil(t, w.Reload())
+	assert.Equal(t, logger.Flags(), Llevel|Lsort|Ldebug)
+	assert.Equal(t, logger.Level(), LevelWarn)
+	_, ok := logger.Emitter().(*FormatWriterStructured)
+	assert.True(t, ok)
+
+	// changed settings are applied
+	writeConfigFile(t, cfgPath, `{"format": "json", "flags": "Lsort", "level": "error", "outputs": [{"type": "file", "path": "`+logPath+`"}]}`)
+	assert.Nil(t, w.Reload())
+	assert.Equal(t, logger.Flags(), Lsort|Ldebug)
+	assert.Equal(t, logger.Level(), LevelError)
+	_, ok = logger.Emitter().(*FormatWriterJSON)
+	assert.True(t, ok)
+}
+
+func TestConfigWatcherPoll(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "log.json")
+	logPath := filepath.Join(dir, "app.log")
+
+	writeConfigFile(t, cfgPath, `{"outputs": [{"type": "file", "path": "`+logPath+`"}]}`)
+	w, err := WatchConfig(cfgPath, 10*time.Millisecond)
+	assert.Nil(t, err)
+	defer w.Close()
+
+	writeConfigFile(t, cfgPath, `{"flags": "Ldebug", "outputs": [{"type": "file", "path": "`+logPath+`"}]}`)
+	waitFor(t, w.Logger().HasDebug)
+
+	// an invalid file is not loaded, and the config is kept
+	writeConfigFile(t, cfgPath, `{"flags": "Ldebug|Lnope", "outputs": [{"type": "file", "path": "`+logPath+`"}]}`)
+	waitFor(t, func() bool {
+		return strings.Contains(readLogFile(t, logPath), "config reload failed")
+	})
+	assert.Equal(t, w.Logger().Flags(), Ldebug)
+}
+
+func TestWatchConfigInvalid(t *testing.T) {
+	dir := t.TempDir()
+	_, err := WatchConfig(filepath.Join(dir, "missing.json"), time.Second)
+	assert.NotNil(t, err)
+
+	cfgPath := filepath.Join(dir, "log.json")
+	writeConfigFile(t, cfgPath, `{"level": "loud"}`)
+	_, err = WatchConfig(cfgPath, time.Second)
+	assert.Equal(t, err.Error(), `mlog: unknown level "loud"`)
+}
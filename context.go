@@ -0,0 +1,78 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mlog
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx that carries logger.
+func NewContext(ctx context.Context, logger *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the Logger carried by ctx, or DefaultLogger if ctx
+// does not carry one.
+func FromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(contextKey{}).(*Logger); ok && logger != nil {
+			return logger
+		}
+	}
+	return DefaultLogger
+}
+
+// A ContextExtractor returns Attrs extracted from a context.Context, such as
+// a request id or a tenant id. It should return nil if ctx carries nothing of
+// interest.
+type ContextExtractor func(ctx context.Context) []*Attr
+
+var (
+	extractorsMu sync.Mutex
+	extractors   atomic.Pointer[[]ContextExtractor]
+)
+
+// RegisterContextExtractor adds fn to the set of ContextExtractors that are
+// applied to every event logged with a context, such as with Logger.InfoxCtx.
+func RegisterContextExtractor(fn ContextExtractor) {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+
+	var fns []ContextExtractor
+	if p := extractors.Load(); p != nil {
+		fns = append(fns, *p...)
+	}
+	fns = append(fns, fn)
+	extractors.Store(&fns)
+}
+
+// contextAttrs prepends the Attrs extracted from ctx by the registered
+// ContextExtractors to attrs.
+func contextAttrs(ctx context.Context, attrs []*Attr) []*Attr {
+	p := extractors.Load()
+	if p == nil || ctx == nil {
+		return attrs
+	}
+
+	var extracted []*Attr
+	for _, fn := range *p {
+		extracted = append(extracted, fn(ctx)...)
+	}
+	if len(extracted) == 0 {
+		return attrs
+	}
+	return append(extracted, attrs...)
+}
+
+// resetContextExtractors removes all registered ContextExtractors.
+func resetContextExtractors() {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	extractors.Store(nil)
+}
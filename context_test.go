@@ -0,0 +1,58 @@
+package mlog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/dropwhile/assert"
+)
+
+type testRequestIDKey struct{}
+
+func TestContextLogger(t *testing.T) {
+	ctx := context.Background()
+	assert.True(t, FromContext(ctx) == DefaultLogger, "expected default logger")
+
+	logger := New(&bytes.Buffer{}, 0)
+	ctx = NewContext(ctx, logger)
+	assert.True(t, FromContext(ctx) == logger, "expected context logger")
+}
+
+func TestContextExtractors(t *testing.T) {
+	t.Cleanup(resetContextExtractors)
+	RegisterContextExtractor(func(ctx context.Context) []*Attr {
+		if id, ok := ctx.Value(testRequestIDKey{}).(string); ok {
+			return []*Attr{A("request_id", id)}
+		}
+		return nil
+	})
+
+	ctx := context.WithValue(context.Background(), testRequestIDKey{}, "abc")
+	buf := &bytes.Buffer{}
+	for name, tt := range map[string]struct {
+		emitter Emitter
+		output  string
+	}{
+		"structured": {&FormatWriterStructured{}, `msg="test" x="y" request_id="abc" z="1"`},
+		"json":       {&FormatWriterJSON{}, `{"msg": "test", "extra": {"x": "y", "request_id": "abc", "z": "1"}}`},
+		"plain":      {&FormatWriterPlain{}, `test x="y" request_id="abc" z="1"`},
+	} {
+		buf.Truncate(0)
+		logger := NewFormatLogger(buf, 0, tt.emitter).With(A("x", "y"))
+		logger.InfoxCtx(ctx, "test", A("z", "1"))
+		assert.Equal(t, buf.String(), tt.output+"\n", fmt.Sprintf("%s: did not match expectation", name))
+
+		// package level functions log to the context logger
+		buf.Truncate(0)
+		InfoxCtx(NewContext(ctx, logger), "test", A("z", "1"))
+		assert.Equal(t, buf.String(), tt.output+"\n", fmt.Sprintf("%s: did not match expectation", name))
+	}
+
+	// contexts without a request id are unaffected
+	buf.Truncate(0)
+	logger := New(buf, 0)
+	logger.InfoxCtx(context.Background(), "test")
+	assert.Equal(t, buf.String(), "msg=\"test\"\n")
+}
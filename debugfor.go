@@ -0,0 +1,125 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mlog
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// debugEscalation holds the state of a temporary debug escalation started
+// with EnableDebugFor.
+type debugEscalation struct {
+	mu    sync.Mutex
+	timer *time.Timer
+	// gen is incremented on each escalation, so a stale timer does not revert
+	// a newer escalation
+	gen uint64
+	// prevDebug is true if Ldebug was set before the escalation
+	prevDebug bool
+}
+
+// EnableDebugFor sets the Ldebug flag for duration d, after which the Ldebug
+// flag is restored to its previous state. Calling EnableDebugFor during an
+// escalation restarts it with the new duration.
+//
+// An info record is logged when the escalation starts and ends, even if the
+// info level is not enabled.
+func (l *Logger) EnableDebugFor(d time.Duration) {
+	esc := &l.debugEsc
+	esc.mu.Lock()
+	defer esc.mu.Unlock()
+
+	if esc.timer != nil {
+		esc.timer.Stop()
+	} else {
+		esc.prevDebug = l.core.flagsHave(Ldebug)
+		l.core.setFlag(Ldebug, true)
+	}
+
+	esc.gen++
+	gen := esc.gen
+	esc.timer = time.AfterFunc(d, func() {
+		l.revertDebug(gen)
+	})
+
+	l.emitAttrs(LevelInfo, "debug logging enabled", Duration("duration", d))
+}
+
+// RevertDebug ends a debug escalation started with EnableDebugFor, restoring
+// the Ldebug flag to its previous state. It does nothing if there is no
+// escalation.
+func (l *Logger) RevertDebug() {
+	l.revertDebug(0)
+}
+
+// revertDebug ends the current debug escalation. If gen is not zero, the
+// escalation is only ended if it is still generation gen.
+func (l *Logger) revertDebug(gen uint64) {
+	esc := &l.debugEsc
+	esc.mu.Lock()
+	defer esc.mu.Unlock()
+
+	if esc.timer == nil || (gen != 0 && gen != esc.gen) {
+		return
+	}
+	esc.timer.Stop()
+	esc.timer = nil
+	l.core.setFlag(Ldebug, esc.prevDebug)
+
+	l.emitAttrs(LevelInfo, "debug logging reverted")
+}
+
+// HandleDebugSignals starts a goroutine that toggles a debug escalation of
+// duration d when the toggle signal is received, and reverts it when the
+// revert signal is received, as in:
+//
+//	stop := logger.HandleDebugSignals(10*time.Minute, syscall.SIGUSR1, syscall.SIGUSR2)
+//	defer stop()
+//
+// The revert signal may be nil. The returned function stops handling the
+// signals. See EnableDebugFor.
+func (l *Logger) HandleDebugSignals(d time.Duration, toggle, revert os.Signal) func() {
+	sigs := []os.Signal{toggle}
+	if revert != nil {
+		sigs = append(sigs, revert)
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-ch:
+				switch {
+				case sig == toggle && !l.debugEscalated():
+					l.EnableDebugFor(d)
+				default:
+					l.RevertDebug()
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// debugEscalated returns true if a debug escalation is active.
+func (l *Logger) debugEscalated() bool {
+	l.debugEsc.mu.Lock()
+	defer l.debugEsc.mu.Unlock()
+	return l.debugEsc.timer != nil
+}
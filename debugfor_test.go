@@ -0,0 +1,96 @@
+package mlog
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dropwhile/assert"
+)
+
+// syncBuffer is a bytes.Buffer that is safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func waitFor(t *testing.T, f func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !f() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestEnableDebugFor(t *testing.T) {
+	buf := &syncBuffer{}
+	logger := New(buf, Llevel|Lsort)
+	logger.SetLevel(LevelError)
+
+	logger.EnableDebugFor(time.Hour)
+	// restarts the escalation, with a new timer
+	logger.EnableDebugFor(time.Hour)
+	assert.True(t, logger.HasDebug())
+	logger.Debugx("test")
+	// other flags changed during the escalation are kept
+	logger.SetFlags(logger.Flags() | Ltimestamp)
+
+	// the stopped timer of the first escalation does not revert it
+	gen := logger.debugEsc.gen
+	logger.revertDebug(gen - 1)
+	assert.True(t, logger.HasDebug())
+
+	// expire the escalation, as its timer does
+	logger.revertDebug(gen)
+	assert.False(t, logger.HasDebug())
+	assert.Equal(t, logger.Flags(), Llevel|Lsort|Ltimestamp)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, len(lines), 4)
+	assert.Equal(t, lines[0], `level="I" msg="debug logging enabled" duration="1h0m0s"`)
+	assert.Equal(t, lines[1], lines[0])
+	assert.Equal(t, lines[2], `level="D" msg="test"`)
+	assert.MatchesRegex(t, lines[3], `level="I" msg="debug logging reverted"$`)
+}
+
+func TestEnableDebugForRevert(t *testing.T) {
+	buf := &syncBuffer{}
+	logger := New(buf, 0)
+
+	logger.EnableDebugFor(time.Hour)
+	// restarts the escalation
+	logger.EnableDebugFor(time.Hour)
+	assert.True(t, logger.HasDebug())
+	logger.RevertDebug()
+	assert.False(t, logger.HasDebug())
+	// no escalation to revert
+	logger.RevertDebug()
+
+	assert.Equal(t, buf.String(), strings.Join([]string{
+		`msg="debug logging enabled" duration="1h0m0s"`,
+		`msg="debug logging enabled" duration="1h0m0s"`,
+		`msg="debug logging reverted"`,
+	}, "\n")+"\n")
+
+	// Ldebug stays set if it was set before the escalation
+	logger.SetFlags(Ldebug)
+	logger.EnableDebugFor(time.Hour)
+	logger.RevertDebug()
+	assert.True(t, logger.HasDebug())
+}
@@ -0,0 +1,26 @@
+//go:build unix
+
+package mlog
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHandleDebugSignals(t *testing.T) {
+	logger := New(&syncBuffer{}, 0)
+	stop := logger.HandleDebugSignals(time.Hour, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer stop()
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	waitFor(t, logger.HasDebug)
+	// toggles the escalation off
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	waitFor(t, func() bool { return !logger.HasDebug() })
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	waitFor(t, logger.HasDebug)
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR2)
+	waitFor(t, func() bool { return !logger.HasDebug() })
+}
@@ -1,9 +1,11 @@
 package mlog
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"time"
 )
 
 // DefaultLogger is the default package level Logger
@@ -29,128 +31,313 @@ func SetFlags(flags FlagSet) {
 	DefaultLogger.SetFlags(flags)
 }
 
+// Named returns a new named child Logger of the default Logger.
+// See Logger.Named
+func Named(name string) *Logger {
+	return DefaultLogger.Named(name)
+}
+
+// EnableDebugFor sets the Ldebug flag for the default Logger for duration d.
+// See Logger.EnableDebugFor
+func EnableDebugFor(d time.Duration) {
+	DefaultLogger.EnableDebugFor(d)
+}
+
+// RevertDebug ends a debug escalation of the default Logger.
+// See Logger.RevertDebug
+func RevertDebug() {
+	DefaultLogger.RevertDebug()
+}
+
+// SetLevel sets the minimum Level for the default Logger. See
+// Logger.SetLevel.
+func SetLevel(level Level) {
+	DefaultLogger.SetLevel(level)
+}
+
+// Enabled returns true if events at level are logged by the default Logger.
+// See Logger.Enabled.
+func Enabled(level Level) bool {
+	return DefaultLogger.Enabled(level)
+}
+
 // HasDebug returns true if the default Logger has debug logging FlagSet enabled.
 // See Logger.HasDebug
 func HasDebug() bool {
 	return DefaultLogger.HasDebug()
 }
 
+// With returns a child of the default Logger, with attrs bound to it.
+// See Logger.With
+func With(attrs ...*Attr) *Logger {
+	return DefaultLogger.With(attrs...)
+}
+
 // Debugx logs to the default Logger. See Logger.Debugm
 func Debugx(message string, attrs ...*Attr) {
-	if DefaultLogger.HasDebug() {
-		DefaultLogger.EmitAttrs(-1, message, attrs...)
+	if DefaultLogger.debugEnabled() {
+		DefaultLogger.emitAttrs(LevelDebug, message, attrs...)
 	}
 }
 
 // Infox logs to the default Logger. See Logger.Infom
 func Infox(message string, attrs ...*Attr) {
-	DefaultLogger.EmitAttrs(0, message, attrs...)
+	if DefaultLogger.Enabled(LevelInfo) {
+		DefaultLogger.emitAttrs(LevelInfo, message, attrs...)
+	}
 }
 
 // Printx logs to the default Logger. See Logger.Printm
 func Printx(message string, attrs ...*Attr) {
-	DefaultLogger.EmitAttrs(0, message, attrs...)
+	if DefaultLogger.Enabled(LevelInfo) {
+		DefaultLogger.emitAttrs(LevelInfo, message, attrs...)
+	}
+}
+
+// Warnx logs to the default Logger. See Logger.Warnx
+func Warnx(message string, attrs ...*Attr) {
+	if DefaultLogger.Enabled(LevelWarn) {
+		DefaultLogger.emitAttrs(LevelWarn, message, attrs...)
+	}
+}
+
+// Errorx logs to the default Logger. See Logger.Errorx
+func Errorx(message string, attrs ...*Attr) {
+	if DefaultLogger.Enabled(LevelError) {
+		DefaultLogger.emitAttrs(LevelError, message, attrs...)
+	}
 }
 
 // Fatalx logs to the default Logger. See Logger.Fatalm
 func Fatalx(message string, attrs ...*Attr) {
-	DefaultLogger.EmitAttrs(1, message, attrs...)
-	os.Exit(1)
+	if DefaultLogger.Enabled(LevelFatal) {
+		DefaultLogger.emitAttrs(LevelFatal, message, attrs...)
+	}
+	DefaultLogger.exit()
 }
 
 // Panicx logs to the default Logger. See Logger.Panicm
 func Panicx(message string, attrs ...*Attr) {
-	DefaultLogger.EmitAttrs(1, message, attrs...)
-	panic(message)
+	if DefaultLogger.Enabled(LevelFatal) {
+		DefaultLogger.emitAttrs(LevelFatal, message, attrs...)
+	}
+	DefaultLogger.panic(message)
+}
+
+// DebugxCtx logs to the Logger carried by ctx, or the default Logger.
+// See Logger.DebugxCtx
+func DebugxCtx(ctx context.Context, message string, attrs ...*Attr) {
+	logger := FromContext(ctx)
+	if logger.debugEnabled() {
+		logger.emitAttrs(LevelDebug, message, contextAttrs(ctx, attrs)...)
+	}
+}
+
+// InfoxCtx logs to the Logger carried by ctx, or the default Logger.
+// See Logger.InfoxCtx
+func InfoxCtx(ctx context.Context, message string, attrs ...*Attr) {
+	if logger := FromContext(ctx); logger.Enabled(LevelInfo) {
+		logger.emitAttrs(LevelInfo, message, contextAttrs(ctx, attrs)...)
+	}
+}
+
+// PrintxCtx logs to the Logger carried by ctx, or the default Logger.
+// See Logger.PrintxCtx
+func PrintxCtx(ctx context.Context, message string, attrs ...*Attr) {
+	if logger := FromContext(ctx); logger.Enabled(LevelInfo) {
+		logger.emitAttrs(LevelInfo, message, contextAttrs(ctx, attrs)...)
+	}
+}
+
+// WarnxCtx logs to the Logger carried by ctx, or the default Logger.
+// See Logger.WarnxCtx
+func WarnxCtx(ctx context.Context, message string, attrs ...*Attr) {
+	if logger := FromContext(ctx); logger.Enabled(LevelWarn) {
+		logger.emitAttrs(LevelWarn, message, contextAttrs(ctx, attrs)...)
+	}
+}
+
+// ErrorxCtx logs to the Logger carried by ctx, or the default Logger.
+// See Logger.ErrorxCtx
+func ErrorxCtx(ctx context.Context, message string, attrs ...*Attr) {
+	if logger := FromContext(ctx); logger.Enabled(LevelError) {
+		logger.emitAttrs(LevelError, message, contextAttrs(ctx, attrs)...)
+	}
+}
+
+// FatalxCtx logs to the Logger carried by ctx, or the default Logger.
+// See Logger.FatalxCtx
+func FatalxCtx(ctx context.Context, message string, attrs ...*Attr) {
+	logger := FromContext(ctx)
+	if logger.Enabled(LevelFatal) {
+		logger.emitAttrs(LevelFatal, message, contextAttrs(ctx, attrs)...)
+	}
+	logger.exit()
+}
+
+// PanicxCtx logs to the Logger carried by ctx, or the default Logger.
+// See Logger.PanicxCtx
+func PanicxCtx(ctx context.Context, message string, attrs ...*Attr) {
+	logger := FromContext(ctx)
+	if logger.Enabled(LevelFatal) {
+		logger.emitAttrs(LevelFatal, message, contextAttrs(ctx, attrs)...)
+	}
+	logger.panic(message)
 }
 
 // Debugm logs to the default Logger. See Logger.Debugm
 func Debugm(message string, v Map) {
-	if DefaultLogger.HasDebug() {
-		DefaultLogger.Emit(-1, message, v)
+	if DefaultLogger.debugEnabled() {
+		DefaultLogger.emit(LevelDebug, message, v)
 	}
 }
 
 // Infom logs to the default Logger. See Logger.Infom
 func Infom(message string, v Map) {
-	DefaultLogger.Emit(0, message, v)
+	if DefaultLogger.Enabled(LevelInfo) {
+		DefaultLogger.emit(LevelInfo, message, v)
+	}
 }
 
 // Printm logs to the default Logger. See Logger.Printm
 func Printm(message string, v Map) {
-	DefaultLogger.Emit(0, message, v)
+	if DefaultLogger.Enabled(LevelInfo) {
+		DefaultLogger.emit(LevelInfo, message, v)
+	}
+}
+
+// Warnm logs to the default Logger. See Logger.Warnm
+func Warnm(message string, v Map) {
+	if DefaultLogger.Enabled(LevelWarn) {
+		DefaultLogger.emit(LevelWarn, message, v)
+	}
+}
+
+// Errorm logs to the default Logger. See Logger.Errorm
+func Errorm(message string, v Map) {
+	if DefaultLogger.Enabled(LevelError) {
+		DefaultLogger.emit(LevelError, message, v)
+	}
 }
 
 // Fatalm logs to the default Logger. See Logger.Fatalm
 func Fatalm(message string, v Map) {
-	DefaultLogger.Emit(1, message, v)
-	os.Exit(1)
+	if DefaultLogger.Enabled(LevelFatal) {
+		DefaultLogger.emit(LevelFatal, message, v)
+	}
+	DefaultLogger.exit()
 }
 
 // Panicm logs to the default Logger. See Logger.Panicm
 func Panicm(message string, v Map) {
-	DefaultLogger.Emit(1, message, v)
-	panic(message)
+	if DefaultLogger.Enabled(LevelFatal) {
+		DefaultLogger.emit(LevelFatal, message, v)
+	}
+	DefaultLogger.panic(message)
 }
 
 // Debugf logs to the default Logger. See Logger.Debugf
 func Debugf(format string, v ...interface{}) {
-	if DefaultLogger.HasDebug() {
-		DefaultLogger.Emit(-1, fmt.Sprintf(format, v...), nil)
+	if DefaultLogger.debugEnabled() {
+		DefaultLogger.emit(LevelDebug, fmt.Sprintf(format, v...), nil)
 	}
 }
 
 // Infof logs to the default Logger. See Logger.Infof
 func Infof(format string, v ...interface{}) {
-	DefaultLogger.Emit(0, fmt.Sprintf(format, v...), nil)
+	if DefaultLogger.Enabled(LevelInfo) {
+		DefaultLogger.emit(LevelInfo, fmt.Sprintf(format, v...), nil)
+	}
 }
 
 // Printf logs to the default Logger. See Logger.Printf
 func Printf(format string, v ...interface{}) {
-	DefaultLogger.Emit(0, fmt.Sprintf(format, v...), nil)
+	if DefaultLogger.Enabled(LevelInfo) {
+		DefaultLogger.emit(LevelInfo, fmt.Sprintf(format, v...), nil)
+	}
+}
+
+// Warnf logs to the default Logger. See Logger.Warnf
+func Warnf(format string, v ...interface{}) {
+	if DefaultLogger.Enabled(LevelWarn) {
+		DefaultLogger.emit(LevelWarn, fmt.Sprintf(format, v...), nil)
+	}
+}
+
+// Errorf logs to the default Logger. See Logger.Errorf
+func Errorf(format string, v ...interface{}) {
+	if DefaultLogger.Enabled(LevelError) {
+		DefaultLogger.emit(LevelError, fmt.Sprintf(format, v...), nil)
+	}
 }
 
 // Fatalf logs to the default Logger. See Logger.Fatalf
 func Fatalf(format string, v ...interface{}) {
-	DefaultLogger.Emit(1, fmt.Sprintf(format, v...), nil)
-	os.Exit(1)
+	if DefaultLogger.Enabled(LevelFatal) {
+		DefaultLogger.emit(LevelFatal, fmt.Sprintf(format, v...), nil)
+	}
+	DefaultLogger.exit()
 }
 
 // Panicf is equivalent to Printf() followed by a call to panic().
 // See Logger.Panicf
 func Panicf(format string, v ...interface{}) {
 	s := fmt.Sprintf(format, v...)
-	DefaultLogger.Emit(1, s, nil)
-	panic(s)
+	if DefaultLogger.Enabled(LevelFatal) {
+		DefaultLogger.emit(LevelFatal, s, nil)
+	}
+	DefaultLogger.panic(s)
 }
 
 // Debug logs to the default Logger. See Logger.Debug
 func Debug(v ...interface{}) {
-	if DefaultLogger.HasDebug() {
-		DefaultLogger.Emit(-1, fmt.Sprint(v...), nil)
+	if DefaultLogger.debugEnabled() {
+		DefaultLogger.emit(LevelDebug, fmt.Sprint(v...), nil)
 	}
 }
 
 // Info logs to the default Logger. See Logger.Info
 func Info(v ...interface{}) {
-	DefaultLogger.Emit(0, fmt.Sprint(v...), nil)
+	if DefaultLogger.Enabled(LevelInfo) {
+		DefaultLogger.emit(LevelInfo, fmt.Sprint(v...), nil)
+	}
 }
 
 // Print logs to the default Logger. See Logger.Print
 func Print(v ...interface{}) {
-	DefaultLogger.Emit(0, fmt.Sprint(v...), nil)
+	if DefaultLogger.Enabled(LevelInfo) {
+		DefaultLogger.emit(LevelInfo, fmt.Sprint(v...), nil)
+	}
+}
+
+// Warn logs to the default Logger. See Logger.Warn
+func Warn(v ...interface{}) {
+	if DefaultLogger.Enabled(LevelWarn) {
+		DefaultLogger.emit(LevelWarn, fmt.Sprint(v...), nil)
+	}
+}
+
+// Error logs to the default Logger. See Logger.Error
+func Error(v ...interface{}) {
+	if DefaultLogger.Enabled(LevelError) {
+		DefaultLogger.emit(LevelError, fmt.Sprint(v...), nil)
+	}
 }
 
 // Fatal logs to the default Logger. See Logger.Fatal
 func Fatal(v ...interface{}) {
-	DefaultLogger.Emit(1, fmt.Sprint(v...), nil)
-	os.Exit(1)
+	if DefaultLogger.Enabled(LevelFatal) {
+		DefaultLogger.emit(LevelFatal, fmt.Sprint(v...), nil)
+	}
+	DefaultLogger.exit()
 }
 
 // Panic is equivalent to Print() followed by a call to panic().
 // See Logger.Panic
 func Panic(v ...interface{}) {
 	s := fmt.Sprint(v...)
-	DefaultLogger.Emit(1, s, nil)
-	panic(s)
+	if DefaultLogger.Enabled(LevelFatal) {
+		DefaultLogger.emit(LevelFatal, s, nil)
+	}
+	DefaultLogger.panic(s)
 }
@@ -5,11 +5,16 @@
 /*
 Package mlog provides a purposefully basic logging library for Go.
 
-mlog only has 3 logging levels: debug, info, and fatal.
+mlog has 5 logging levels: debug, info, warn, error, and fatal.
 
-Each logging level has 3 logging methods. As an example, the following methods
-log at the "info" level: Info, Infof, Infom. There are similar methods for
-the fatal and debug levels.
+Each logging level has 4 logging methods. As an example, the following methods
+log at the "info" level: Info, Infof, Infom, Infox. There are similar methods
+for the other levels. Only the fatal methods exit.
+
+The x methods also have a Ctx variant, such as InfoxCtx, that adds the Attrs
+extracted from a context.Context by the registered ContextExtractors. The
+other methods intentionally have no Ctx variant; use the x methods to log with
+a context. FromContext returns the Logger carried by a context.
 
 Example usage:
 
@@ -5,6 +5,9 @@
 package mlog
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
 	"sort"
 	"strings"
 )
@@ -69,6 +72,11 @@ func (f FlagSet) GoString() string {
 // String fulfills the Stringer interface, defining the format used for
 // the %s format string.
 func (f FlagSet) String() string {
+	return "FlagSet(" + strings.Join(f.names(), "|") + ")"
+}
+
+// names returns the sorted names of the flags in the set.
+func (f FlagSet) names() []string {
 	flags := make([]string, 0, len(flagNames))
 	for k, v := range flagNames {
 		if f&k != 0 {
@@ -76,5 +84,97 @@ func (f FlagSet) String() string {
 		}
 	}
 	sort.Strings(flags)
-	return "FlagSet(" + strings.Join(flags, "|") + ")"
+	return flags
+}
+
+// EnvFlags is the conventional environment variable name for FlagSetFromEnv.
+const EnvFlags = "MLOG_FLAGS"
+
+// ParseFlagSet parses a list of flag names, separated by '|' or ',', as in
+// "Ltimestamp|Llevel|Ldebug". Names are case insensitive, and "Lstd" is
+// accepted for Lstd. The "FlagSet(...)" form returned by String is also
+// accepted. An empty string is an empty FlagSet.
+func ParseFlagSet(s string) (FlagSet, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "FlagSet(") && strings.HasSuffix(s, ")") {
+		s = s[len("FlagSet(") : len(s)-1]
+	}
+
+	var flags FlagSet
+	for _, name := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == '|' || r == ','
+	}) {
+		name = strings.TrimSpace(name)
+		f, ok := lookupFlag(name)
+		if !ok {
+			return 0, fmt.Errorf("mlog: unknown flag %q", name)
+		}
+		flags |= f
+	}
+	return flags, nil
+}
+
+func lookupFlag(name string) (FlagSet, bool) {
+	if strings.EqualFold(name, "Lstd") {
+		return Lstd, true
+	}
+	for k, v := range flagNames {
+		if strings.EqualFold(name, v) {
+			return k, true
+		}
+	}
+	return 0, false
+}
+
+// FlagSetFromEnv parses the environment variable key with ParseFlagSet, as
+// in:
+//
+//	flags, err := mlog.FlagSetFromEnv(mlog.EnvFlags, mlog.Lstd)
+//
+// If the variable is not set, def is returned.
+func FlagSetFromEnv(key string, def FlagSet) (FlagSet, error) {
+	s, ok := os.LookupEnv(key)
+	if !ok {
+		return def, nil
+	}
+	f, err := ParseFlagSet(s)
+	if err != nil {
+		return def, fmt.Errorf("%w in %s", err, key)
+	}
+	return f, nil
+}
+
+// Set fulfills the flag.Value interface, so a FlagSet can be set from a
+// command line flag, as in:
+//
+//	flags := mlog.Lstd
+//	flag.Var(&flags, "log-flags", "log flags")
+//
+// See ParseFlagSet for the accepted format.
+func (f *FlagSet) Set(s string) error {
+	flags, err := ParseFlagSet(s)
+	if err != nil {
+		return err
+	}
+	*f = flags
+	return nil
+}
+
+// MarshalText fulfills the encoding.TextMarshaler interface. The flag names
+// are joined by '|', as in "Llevel|Ltimestamp".
+func (f FlagSet) MarshalText() ([]byte, error) {
+	return []byte(strings.Join(f.names(), "|")), nil
+}
+
+// UnmarshalText fulfills the encoding.TextUnmarshaler interface. See
+// ParseFlagSet for the accepted format.
+func (f *FlagSet) UnmarshalText(text []byte) error {
+	return f.Set(string(text))
+}
+
+// MarshalJSON fulfills the json.Marshaler interface. A FlagSet is encoded as
+// a json string, as with MarshalText.
+func (f FlagSet) MarshalJSON() ([]byte, error) {
+	text, _ := f.MarshalText()
+	return json.Marshal(string(text))
 }
@@ -1,8 +1,12 @@
 package mlog
 
 import (
+	"encoding/json"
+	"flag"
 	"io"
 	"testing"
+
+	"github.com/dropwhile/assert"
 )
 
 func TestFlagSet(t *testing.T) {
@@ -27,3 +31,84 @@ func TestFlagSet(t *testing.T) {
 			"actual:", flags.GoString())
 	}
 }
+
+func TestParseFlagSet(t *testing.T) {
+	var tests = []struct {
+		input string
+		want  FlagSet
+	}{
+		{"", 0},
+		{"Ltimestamp|Llevel|Ldebug", Ltimestamp | Llevel | Ldebug},
+		{"Lshortfile, Lsort", Lshortfile | Lsort},
+		{"lstd|ldebug", Lstd | Ldebug},
+		{"FlagSet(Llevel|Lsort)", Llevel | Lsort},
+		{(Llongfile | Ltai64n).String(), Llongfile | Ltai64n},
+	}
+
+	for _, tc := range tests {
+		flags, err := ParseFlagSet(tc.input)
+		assert.Nil(t, err, tc.input)
+		assert.Equal(t, flags, tc.want, tc.input)
+	}
+
+	_, err := ParseFlagSet("Llevel|Lnope")
+	assert.Equal(t, err.Error(), `mlog: unknown flag "Lnope"`)
+}
+
+func TestFlagSetFlagValue(t *testing.T) {
+	flags := Lstd
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(&flags, "log-flags", "")
+
+	assert.Nil(t, fs.Parse([]string{"-log-flags", "Llevel|Ldebug"}))
+	assert.Equal(t, flags, Llevel|Ldebug)
+	assert.NotNil(t, fs.Parse([]string{"-log-flags", "Lnope"}))
+	assert.Equal(t, flags, Llevel|Ldebug)
+}
+
+func TestFlagSetMarshal(t *testing.T) {
+	type config struct {
+		Flags FlagSet `json:"flags"`
+	}
+
+	b, err := json.Marshal(config{Flags: Ltimestamp | Llevel})
+	assert.Nil(t, err)
+	assert.Equal(t, string(b), `{"flags":"Llevel|Ltimestamp"}`)
+
+	b, err = json.Marshal(config{})
+	assert.Nil(t, err)
+	assert.Equal(t, string(b), `{"flags":""}`)
+
+	var c config
+	assert.Nil(t, json.Unmarshal([]byte(`{"flags":"Lsort|Ldebug"}`), &c))
+	assert.Equal(t, c.Flags, Lsort|Ldebug)
+	assert.NotNil(t, json.Unmarshal([]byte(`{"flags":"Lnope"}`), &c))
+
+	text, err := (Lshortfile | Lsort).MarshalText()
+	assert.Nil(t, err)
+	var flags FlagSet
+	assert.Nil(t, flags.UnmarshalText(text))
+	assert.Equal(t, flags, Lshortfile|Lsort)
+}
+
+func TestFlagSetFromEnv(t *testing.T) {
+	flags, err := FlagSetFromEnv("MLOG_TEST_UNSET_FLAGS", Lstd)
+	assert.Nil(t, err)
+	assert.Equal(t, flags, Lstd)
+
+	t.Setenv(EnvFlags, "Llevel|Ldebug")
+	flags, err = FlagSetFromEnv(EnvFlags, Lstd)
+	assert.Nil(t, err)
+	assert.Equal(t, flags, Llevel|Ldebug)
+
+	t.Setenv(EnvFlags, "")
+	flags, err = FlagSetFromEnv(EnvFlags, Lstd)
+	assert.Nil(t, err)
+	assert.Equal(t, flags, FlagSet(0))
+
+	t.Setenv(EnvFlags, "Lnope")
+	flags, err = FlagSetFromEnv(EnvFlags, Lstd)
+	assert.Equal(t, err.Error(), `mlog: unknown flag "Lnope" in MLOG_FLAGS`)
+	assert.Equal(t, flags, Lstd)
+}
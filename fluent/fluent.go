@@ -0,0 +1,527 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Package fluent provides an mlog Emitter that sends log events to Fluentd or
+// Fluent Bit, using the Fluent forward protocol.
+//
+// Example usage:
+//
+//	e, err := fluent.New(fluent.Options{
+//	    Address: "127.0.0.1:24224",
+//	    Tag:     "billing.app",
+//	    Mode:    fluent.ForwardMode,
+//	    Ack:     true,
+//	})
+//	if err != nil {
+//	    // handle error
+//	}
+//	defer e.Close()
+//	logger := mlog.NewFormatLogger(io.Discard, mlog.Lshortfile, e)
+//
+// Each event is sent as a record with the level, message, caller (if the
+// Logger has the Llongfile or Lshortfile flag), and extra Attrs, with their
+// values MessagePack encoded as native types. Attrs named level, message or
+// caller are prefixed with "attr_", so they do not replace those. The Logger
+// output is not used.
+package fluent
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cactus/mlog"
+)
+
+// Mode is the forward protocol mode, which determines how events are sent.
+type Mode int
+
+const (
+	// MessageMode sends each event as it is logged.
+	MessageMode Mode = iota
+	// ForwardMode sends events in batches, as an array of entries.
+	ForwardMode
+	// PackedForwardMode sends events in batches, as a binary blob of
+	// concatenated entries.
+	PackedForwardMode
+)
+
+// Defaults for Options.
+const (
+	DefaultTag           = "mlog"
+	DefaultBatchSize     = 100
+	DefaultFlushInterval = time.Second
+	DefaultAckTimeout    = 5 * time.Second
+	DefaultQueueSize     = 10000
+)
+
+const (
+	// dialTimeout is the timeout used to connect.
+	dialTimeout = 5 * time.Second
+	// writeTimeout is the timeout of each write.
+	writeTimeout = 5 * time.Second
+	// minBackoff and maxBackoff bound the wait before resending events
+	// after a failure.
+	minBackoff = 100 * time.Millisecond
+	maxBackoff = 30 * time.Second
+)
+
+// ErrAckMismatch is returned when the ack received for a chunk does not match
+// the chunk id.
+var ErrAckMismatch = errors.New("fluent: ack does not match chunk")
+
+// Options configures an Emitter.
+type Options struct {
+	// Network is tcp (the default) or unix.
+	Network string
+	// Address is the address of the forward input, as with net.Dial.
+	Address string
+	// Tag is the tag of the events. Defaults to DefaultTag.
+	Tag string
+	// Mode is the forward protocol mode. Defaults to MessageMode.
+	Mode Mode
+	// BatchSize is the maximum number of events sent together, in
+	// ForwardMode and PackedForwardMode. Defaults to DefaultBatchSize.
+	BatchSize int
+	// FlushInterval is the maximum time an event is held before it is sent,
+	// in ForwardMode and PackedForwardMode. Defaults to
+	// DefaultFlushInterval.
+	FlushInterval time.Duration
+	// Ack requests an acknowledgment for each message. Events are only
+	// removed from the queue once acknowledged, for at-least-once delivery.
+	Ack bool
+	// AckTimeout is how long to wait for an acknowledgment. Defaults to
+	// DefaultAckTimeout.
+	AckTimeout time.Duration
+	// QueueSize is the maximum number of events waiting to be sent. Events
+	// logged while the queue is full are dropped. Defaults to
+	// DefaultQueueSize.
+	QueueSize int
+}
+
+// Emitter is an mlog Emitter that sends log events using the Fluent forward
+// protocol. Events are queued, and sent by a background goroutine, so that
+// logging never waits on the network. If sending fails, the Emitter
+// reconnects, and resends the events with exponential backoff; events stay
+// queued until they are sent (and acknowledged, with Options.Ack). It is
+// safe for concurrent use.
+type Emitter struct {
+	network    string
+	address    string
+	tag        string
+	mode       Mode
+	batchSize  int
+	ack        bool
+	ackTimeout time.Duration
+	queueSize  int
+
+	// only used by the sender: the run goroutine, or Close once it has
+	// stopped
+	conn net.Conn
+	br   *bufio.Reader
+
+	mu      sync.Mutex
+	queue   [][]byte // encoded entries, oldest first
+	closed  bool
+	dropped atomic.Uint64
+
+	wake  chan struct{}
+	flush chan chan error
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// New connects to the forward input described by opts, and returns an
+// Emitter sending to it.
+func New(opts Options) (*Emitter, error) {
+	if opts.Network == "" {
+		opts.Network = "tcp"
+	}
+	if opts.Tag == "" {
+		opts.Tag = DefaultTag
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = DefaultFlushInterval
+	}
+	if opts.AckTimeout <= 0 {
+		opts.AckTimeout = DefaultAckTimeout
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultQueueSize
+	}
+	if opts.Mode == MessageMode {
+		opts.BatchSize = 1
+	}
+
+	e := &Emitter{
+		network:    opts.Network,
+		address:    opts.Address,
+		tag:        opts.Tag,
+		mode:       opts.Mode,
+		batchSize:  opts.BatchSize,
+		ack:        opts.Ack,
+		ackTimeout: opts.AckTimeout,
+		queueSize:  opts.QueueSize,
+		wake:       make(chan struct{}, 1),
+		flush:      make(chan chan error),
+		done:       make(chan struct{}),
+	}
+	if err := e.connect(); err != nil {
+		return nil, err
+	}
+	e.wg.Add(1)
+	go e.run(opts.FlushInterval)
+	return e, nil
+}
+
+func (e *Emitter) connect() error {
+	conn, err := net.DialTimeout(e.network, e.address, dialTimeout)
+	if err != nil {
+		return err
+	}
+	e.conn = conn
+	e.br = bufio.NewReader(conn)
+	return nil
+}
+
+// run sends queued events: full batches as soon as they are queued, and any
+// others every interval. After a failure, it waits with exponential backoff
+// before sending again.
+func (e *Emitter) run(interval time.Duration) {
+	defer e.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var backoff time.Duration
+	var retry <-chan time.Time // set while backing off
+	send := func(all bool) error {
+		err := e.sendQueued(all)
+		if err != nil {
+			backoff = min(max(2*backoff, minBackoff), maxBackoff)
+			retry = time.After(backoff)
+		} else {
+			backoff, retry = 0, nil
+		}
+		return err
+	}
+
+	for {
+		select {
+		case <-e.wake:
+			if retry == nil {
+				_ = send(false)
+			}
+		case <-ticker.C:
+			if retry == nil {
+				_ = send(true)
+			}
+		case <-retry:
+			_ = send(true)
+		case req := <-e.flush:
+			req <- send(true)
+		case <-e.done:
+			return
+		}
+	}
+}
+
+// Flush sends any queued events, and returns the error of the last attempt
+// to send them, if they could not be sent.
+func (e *Emitter) Flush() error {
+	req := make(chan error, 1)
+	select {
+	case e.flush <- req:
+		return <-req
+	case <-e.done:
+		return nil
+	}
+}
+
+// Dropped returns the number of events dropped so far, as the queue was
+// full.
+func (e *Emitter) Dropped() uint64 {
+	return e.dropped.Load()
+}
+
+// Close makes a last attempt to send any queued events, and closes the
+// connection. Events that could not be sent are dropped.
+func (e *Emitter) Close() error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
+	}
+	e.closed = true
+	e.mu.Unlock()
+
+	close(e.done)
+	e.wg.Wait()
+
+	err := e.sendQueued(true)
+	e.disconnect()
+	return err
+}
+
+// Emit sends a log event (with nillable extra Map). A Logger calls EmitRecord
+// instead.
+func (e *Emitter) Emit(logger *mlog.Logger, level int, message string, extra mlog.Map) {
+	mlog.EmitMapRecord(e, logger, level, message, extra)
+}
+
+// EmitAttrs sends a log event (with optional extra Attrs). A Logger calls
+// EmitRecord instead.
+func (e *Emitter) EmitAttrs(logger *mlog.Logger, level int, message string, extra ...*mlog.Attr) {
+	mlog.EmitAttrsRecord(e, logger, level, message, extra)
+}
+
+// EmitRecord queues r to be sent, waking the sender if a batch is full.
+func (e *Emitter) EmitRecord(logger *mlog.Logger, r mlog.Record) {
+	entry := encodeEntry(logger, r)
+
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return
+	}
+	if len(e.queue) >= e.queueSize {
+		e.mu.Unlock()
+		e.dropped.Add(1)
+		return
+	}
+	e.queue = append(e.queue, entry)
+	full := len(e.queue) >= e.batchSize
+	e.mu.Unlock()
+
+	if full {
+		select {
+		case e.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// sendQueued sends the queued entries in batches, removing each batch from
+// the queue once it is sent. Unless all is true, a last partial batch is
+// left queued. It must only be called by the sender.
+func (e *Emitter) sendQueued(all bool) error {
+	for {
+		e.mu.Lock()
+		n := min(len(e.queue), e.batchSize)
+		if n == 0 || (n < e.batchSize && !all) {
+			e.mu.Unlock()
+			return nil
+		}
+		batch := e.queue[:n:n]
+		e.mu.Unlock()
+
+		var msg []byte
+		switch e.mode {
+		case MessageMode:
+			msg = e.message(batch[0])
+		case PackedForwardMode:
+			msg = e.packedForwardMessage(batch)
+		default:
+			msg = e.forwardMessage(batch)
+		}
+		if err := e.send(msg); err != nil {
+			return err
+		}
+
+		e.mu.Lock()
+		// only the sender removes entries, so the batch is still first
+		clear(e.queue[:n])
+		e.queue = e.queue[n:]
+		e.mu.Unlock()
+	}
+}
+
+// reservedKeys are the record keys set by the Emitter, which Attrs are not
+// sent as.
+var reservedKeys = map[string]bool{
+	"level":   true,
+	"message": true,
+	"caller":  true,
+}
+
+// encodeEntry encodes r as a forward protocol entry: [time, record].
+func encodeEntry(logger *mlog.Logger, r mlog.Record) []byte {
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	var caller string
+	if r.PC != 0 {
+		file, line := r.Caller()
+		if logger != nil && logger.Flags()&mlog.Lshortfile != 0 {
+			file = filepath.Base(file)
+		}
+		caller = file + ":" + strconv.Itoa(line)
+	}
+
+	n := 2 + r.NumAttrs()
+	if caller != "" {
+		n++
+	}
+
+	b := make([]byte, 0, 128)
+	b = appendArrayHeader(b, 2)
+	b = appendEventTime(b, t)
+	b = appendMapHeader(b, n)
+	b = appendString(b, "level")
+	b = appendString(b, r.Level.String())
+	b = appendString(b, "message")
+	b = appendString(b, r.Message)
+	if caller != "" {
+		b = appendString(b, "caller")
+		b = appendString(b, caller)
+	}
+	r.Attrs(func(attr mlog.Attr) bool {
+		if reservedKeys[attr.Key] {
+			b = appendString(b, "attr_"+attr.Key)
+		} else {
+			b = appendString(b, attr.Key)
+		}
+		b = appendAttrValue(b, &attr)
+		return true
+	})
+	return b
+}
+
+// message returns a Message mode message: [tag, time, record, option?].
+func (e *Emitter) message(entry []byte) []byte {
+	n := 3
+	if e.ack {
+		n++
+	}
+	b := appendArrayHeader(nil, n)
+	b = appendString(b, e.tag)
+	// the entry without its array header
+	b = append(b, entry[1:]...)
+	return b
+}
+
+// forwardMessage returns a Forward mode message:
+// [tag, [[time, record], ...], option?].
+func (e *Emitter) forwardMessage(entries [][]byte) []byte {
+	n := 2
+	if e.ack {
+		n++
+	}
+	b := appendArrayHeader(nil, n)
+	b = appendString(b, e.tag)
+	b = appendArrayHeader(b, len(entries))
+	for _, entry := range entries {
+		b = append(b, entry...)
+	}
+	return b
+}
+
+// packedForwardMessage returns a PackedForward mode message:
+// [tag, bin(entries...), option].
+func (e *Emitter) packedForwardMessage(entries [][]byte) []byte {
+	var size int
+	for _, entry := range entries {
+		size += len(entry)
+	}
+	packed := make([]byte, 0, size)
+	for _, entry := range entries {
+		packed = append(packed, entry...)
+	}
+
+	b := appendArrayHeader(nil, 3)
+	b = appendString(b, e.tag)
+	b = appendBinary(b, packed)
+	// the option map is written by send, and always includes the size
+	return appendSizeOption(b, len(entries), e.ack)
+}
+
+// appendSizeOption appends the start of an option map with a size entry,
+// leaving room for a chunk entry if ack is true.
+func appendSizeOption(b []byte, size int, ack bool) []byte {
+	if ack {
+		b = appendMapHeader(b, 2)
+	} else {
+		b = appendMapHeader(b, 1)
+	}
+	b = appendString(b, "size")
+	return appendUint(b, uint64(size))
+}
+
+// send sends msg, connecting first if needed. In ack mode, a chunk option is
+// appended to msg, and the ack is awaited. On failure, the connection is
+// closed. It must only be called by the sender.
+func (e *Emitter) send(msg []byte) error {
+	var chunk string
+	if e.ack {
+		chunk = newChunkID()
+		if e.mode != PackedForwardMode {
+			msg = appendMapHeader(msg, 1)
+		}
+		msg = appendString(msg, "chunk")
+		msg = appendString(msg, chunk)
+	}
+
+	if e.conn == nil {
+		if err := e.connect(); err != nil {
+			return err
+		}
+	}
+	err := e.write(msg, chunk)
+	if err != nil {
+		e.disconnect()
+	}
+	return err
+}
+
+// disconnect closes the connection, if any.
+func (e *Emitter) disconnect() {
+	if e.conn != nil {
+		e.conn.Close()
+		e.conn, e.br = nil, nil
+	}
+}
+
+// write writes msg to the connection, and waits for the ack of chunk, if it
+// is not empty.
+func (e *Emitter) write(msg []byte, chunk string) error {
+	if err := e.conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+	if _, err := e.conn.Write(msg); err != nil {
+		return err
+	}
+	if chunk == "" {
+		return nil
+	}
+
+	if err := e.conn.SetReadDeadline(time.Now().Add(e.ackTimeout)); err != nil {
+		return err
+	}
+	resp, err := decodeValue(e.br)
+	if err != nil {
+		return err
+	}
+	if m, ok := resp.(map[string]interface{}); !ok || m["ack"] != chunk {
+		return ErrAckMismatch
+	}
+	return nil
+}
+
+// newChunkID returns a random, base64 encoded chunk id.
+func newChunkID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return base64.StdEncoding.EncodeToString(id[:])
+}
@@ -0,0 +1,294 @@
+package fluent
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cactus/mlog"
+	"github.com/dropwhile/assert"
+)
+
+var testTime = time.Date(2016, time.January, 11, 12, 13, 14, 15, time.UTC)
+
+var testEventTime = ext{typ: eventTimeExt, data: []byte{0x56, 0x93, 0x9c, 0x5a, 0, 0, 0, 15}}
+
+// testServer is a forward input that passes each decoded message to a
+// channel.
+type testServer struct {
+	ln   net.Listener
+	msgs chan []interface{}
+	// ack is called with each message, and returns the ack to send, if any
+	ack func(msg []interface{}) (interface{}, bool)
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	s := &testServer{ln: ln, msgs: make(chan []interface{}, 100)}
+	t.Cleanup(func() { ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *testServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *testServer) handle(conn net.Conn) {
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+	for {
+		v, err := decodeValue(br)
+		if err != nil {
+			return
+		}
+		msg := v.([]interface{})
+		if s.ack != nil {
+			resp, ok := s.ack(msg)
+			if !ok {
+				return
+			}
+			conn.Write(appendValue(nil, resp))
+		}
+		s.msgs <- msg
+	}
+}
+
+func (s *testServer) next(t *testing.T) []interface{} {
+	t.Helper()
+	select {
+	case msg := <-s.msgs:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+		return nil
+	}
+}
+
+func testRecord(msg string) mlog.Record {
+	r := mlog.NewRecord(testTime, mlog.LevelWarn, msg, 0)
+	r.AddAttrs(mlog.Int("n", -1), mlog.Bool("ok", true), mlog.Float64("f", 0.5))
+	return r
+}
+
+func testRecordMap(msg string) map[string]interface{} {
+	return map[string]interface{}{
+		"level":   "WARN",
+		"message": msg,
+		"service": "billing",
+		"n":       int64(-1),
+		"ok":      true,
+		"f":       0.5,
+	}
+}
+
+func TestEncodeEntryReservedKeys(t *testing.T) {
+	r := mlog.NewRecord(testTime, mlog.LevelInfo, "hello", 0)
+	r.AddAttrs(mlog.String("message", "x"), mlog.String("level", "y"))
+	entry, err := decodeValue(bufio.NewReader(bytes.NewReader(encodeEntry(nil, r))))
+	assert.Nil(t, err)
+	assert.Equal(t, entry.([]interface{})[1], interface{}(map[string]interface{}{
+		"level":        "INFO",
+		"message":      "hello",
+		"attr_message": "x",
+		"attr_level":   "y",
+	}))
+}
+
+func TestEmitterMessageMode(t *testing.T) {
+	s := newTestServer(t)
+	e, err := New(Options{Address: s.ln.Addr().String(), Tag: "app.test"})
+	assert.Nil(t, err)
+	defer e.Close()
+
+	logger := mlog.NewFormatLogger(io.Discard, 0, e).With(mlog.String("service", "billing"))
+	logger.EmitRecord(testRecord("hello"))
+	assert.Equal(t, s.next(t), []interface{}{"app.test", testEventTime, testRecordMap("hello")})
+
+	logger.SetFlags(mlog.Lshortfile)
+	logger.Info("caller")
+	msg := s.next(t)
+	record := msg[2].(map[string]interface{})
+	assert.MatchesRegex(t, record["caller"].(string), `^fluent_test.go:\d+$`)
+}
+
+func TestEmitterForwardMode(t *testing.T) {
+	s := newTestServer(t)
+	e, err := New(Options{
+		Address:       s.ln.Addr().String(),
+		Tag:           "app",
+		Mode:          ForwardMode,
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+	})
+	assert.Nil(t, err)
+	defer e.Close()
+
+	logger := mlog.NewFormatLogger(io.Discard, 0, e).With(mlog.String("service", "billing"))
+	logger.EmitRecord(testRecord("one"))
+	logger.EmitRecord(testRecord("two"))
+	logger.EmitRecord(testRecord("three"))
+	assert.Equal(t, s.next(t), []interface{}{"app", []interface{}{
+		[]interface{}{testEventTime, testRecordMap("one")},
+		[]interface{}{testEventTime, testRecordMap("two")},
+	}})
+
+	assert.Nil(t, e.Flush())
+	assert.Equal(t, s.next(t), []interface{}{"app", []interface{}{
+		[]interface{}{testEventTime, testRecordMap("three")},
+	}})
+}
+
+func TestEmitterFlushInterval(t *testing.T) {
+	s := newTestServer(t)
+	e, err := New(Options{
+		Address:       s.ln.Addr().String(),
+		Mode:          ForwardMode,
+		FlushInterval: 10 * time.Millisecond,
+	})
+	assert.Nil(t, err)
+	defer e.Close()
+
+	mlog.NewFormatLogger(io.Discard, 0, e).Info("test")
+	msg := s.next(t)
+	assert.Equal(t, msg[0], DefaultTag)
+	assert.Equal(t, len(msg[1].([]interface{})), 1)
+}
+
+func TestEmitterPackedForwardMode(t *testing.T) {
+	s := newTestServer(t)
+	e, err := New(Options{Address: s.ln.Addr().String(), Tag: "app", Mode: PackedForwardMode})
+	assert.Nil(t, err)
+
+	logger := mlog.NewFormatLogger(io.Discard, 0, e).With(mlog.String("service", "billing"))
+	logger.EmitRecord(testRecord("one"))
+	logger.EmitRecord(testRecord("two"))
+	// Close flushes
+	assert.Nil(t, e.Close())
+
+	msg := s.next(t)
+	assert.Equal(t, len(msg), 3)
+	assert.Equal(t, msg[0], "app")
+	assert.Equal(t, msg[2], interface{}(map[string]interface{}{"size": uint64(2)}))
+
+	br := bufio.NewReader(strings.NewReader(msg[1].(string)))
+	for _, want := range []string{"one", "two"} {
+		entry, err := decodeValue(br)
+		assert.Nil(t, err)
+		assert.Equal(t, entry, interface{}([]interface{}{testEventTime, testRecordMap(want)}))
+	}
+	_, err = decodeValue(br)
+	assert.Error(t, err, io.EOF)
+}
+
+func TestEmitterAck(t *testing.T) {
+	s := newTestServer(t)
+	var attempts atomic.Int32
+	s.ack = func(msg []interface{}) (interface{}, bool) {
+		// drop the first attempt without an ack
+		if attempts.Add(1) == 1 {
+			return nil, false
+		}
+		option := msg[len(msg)-1].(map[string]interface{})
+		return map[string]interface{}{"ack": option["chunk"]}, true
+	}
+
+	e, err := New(Options{Address: s.ln.Addr().String(), Ack: true, AckTimeout: time.Second})
+	assert.Nil(t, err)
+	defer e.Close()
+
+	logger := mlog.NewFormatLogger(io.Discard, 0, e)
+	logger.Info("test")
+	msg := s.next(t)
+	assert.Equal(t, len(msg), 4)
+	assert.Equal(t, msg[2].(map[string]interface{})["message"], "test")
+	assert.Equal(t, attempts.Load(), 2)
+
+	// packed forward option holds both size and chunk
+	e2, err := New(Options{Address: s.ln.Addr().String(), Ack: true, Mode: PackedForwardMode})
+	assert.Nil(t, err)
+	mlog.NewFormatLogger(io.Discard, 0, e2).Info("test")
+	assert.Nil(t, e2.Close())
+	option := s.next(t)[2].(map[string]interface{})
+	assert.Equal(t, option["size"], interface{}(uint64(1)))
+	assert.Equal(t, len(option["chunk"].(string)), 24)
+}
+
+func TestEmitterAckMismatch(t *testing.T) {
+	s := newTestServer(t)
+	s.ack = func(msg []interface{}) (interface{}, bool) {
+		return map[string]interface{}{"ack": "nope"}, true
+	}
+
+	e, err := New(Options{Address: s.ln.Addr().String(), Ack: true})
+	assert.Nil(t, err)
+	defer e.Close()
+
+	err = e.send(e.message(encodeEntry(nil, testRecord("test"))))
+	assert.Error(t, err, ErrAckMismatch)
+}
+
+func TestEmitterResend(t *testing.T) {
+	s := newTestServer(t)
+	var attempts atomic.Int32
+	s.ack = func(msg []interface{}) (interface{}, bool) {
+		// drop the first attempt without an ack
+		if attempts.Add(1) == 1 {
+			return nil, false
+		}
+		option := msg[len(msg)-1].(map[string]interface{})
+		return map[string]interface{}{"ack": option["chunk"]}, true
+	}
+
+	e, err := New(Options{
+		Address:       s.ln.Addr().String(),
+		Tag:           "app",
+		Mode:          ForwardMode,
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+		Ack:           true,
+		AckTimeout:    time.Second,
+	})
+	assert.Nil(t, err)
+	defer e.Close()
+
+	// the failed batch is kept, and resent as a whole
+	logger := mlog.NewFormatLogger(io.Discard, 0, e).With(mlog.String("service", "billing"))
+	logger.EmitRecord(testRecord("one"))
+	logger.EmitRecord(testRecord("two"))
+	msg := s.next(t)
+	assert.Equal(t, msg[:2], []interface{}{"app", []interface{}{
+		[]interface{}{testEventTime, testRecordMap("one")},
+		[]interface{}{testEventTime, testRecordMap("two")},
+	}})
+	assert.Equal(t, attempts.Load(), 2)
+}
+
+func TestEmitterQueueFull(t *testing.T) {
+	s := newTestServer(t)
+	// never ack, so that nothing leaves the queue
+	s.ack = func(msg []interface{}) (interface{}, bool) { return nil, false }
+
+	e, err := New(Options{Address: s.ln.Addr().String(), Ack: true, QueueSize: 2})
+	assert.Nil(t, err)
+
+	logger := mlog.NewFormatLogger(io.Discard, 0, e)
+	logger.Info("one")
+	logger.Info("two")
+	logger.Info("three")
+	assert.Equal(t, e.Dropped(), 1)
+	assert.Error(t, e.Close(), io.EOF)
+}
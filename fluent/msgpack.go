@@ -0,0 +1,410 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package fluent
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/cactus/mlog"
+)
+
+// A minimal MessagePack encoder and decoder, supporting the types used by the
+// Fluent forward protocol.
+
+// eventTimeExt is the extension type of a Fluent EventTime.
+const eventTimeExt = 0
+
+func appendNil(b []byte) []byte {
+	return append(b, 0xc0)
+}
+
+func appendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 0xc3)
+	}
+	return append(b, 0xc2)
+}
+
+func appendInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0:
+		return appendUint(b, uint64(v))
+	case v >= -32:
+		return append(b, byte(v))
+	case v >= math.MinInt8:
+		return append(b, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(v))
+	case v >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(v))
+	}
+}
+
+func appendUint(b []byte, v uint64) []byte {
+	switch {
+	case v <= 0x7f:
+		return append(b, byte(v))
+	case v <= math.MaxUint8:
+		return append(b, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(v))
+	case v <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), v)
+	}
+}
+
+func appendFloat32(b []byte, v float32) []byte {
+	return binary.BigEndian.AppendUint32(append(b, 0xca), math.Float32bits(v))
+}
+
+func appendFloat64(b []byte, v float64) []byte {
+	return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(v))
+}
+
+func appendString(b []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n <= 31:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+func appendBinary(b []byte, v []byte) []byte {
+	n := len(v)
+	switch {
+	case n <= math.MaxUint8:
+		b = append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xc5), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xc6), uint32(n))
+	}
+	return append(b, v...)
+}
+
+func appendArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n <= 15:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xdc), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xdd), uint32(n))
+	}
+}
+
+func appendMapHeader(b []byte, n int) []byte {
+	switch {
+	case n <= 15:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xde), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xdf), uint32(n))
+	}
+}
+
+// appendEventTime appends t as a Fluent EventTime: a fixext 8 holding the
+// seconds and nanoseconds as big endian uint32s.
+func appendEventTime(b []byte, t time.Time) []byte {
+	b = append(b, 0xd7, eventTimeExt)
+	b = binary.BigEndian.AppendUint32(b, uint32(t.Unix()))
+	return binary.BigEndian.AppendUint32(b, uint32(t.Nanosecond()))
+}
+
+// appendAttrValue appends the value of attr, keeping the type of values
+// created with the typed Attr constructors.
+func appendAttrValue(b []byte, attr *mlog.Attr) []byte {
+	return appendValue(b, attr.Any())
+}
+
+// appendValue appends v. Types without a MessagePack representation are
+// appended as strings: errors with Error, times in RFC 3339 format, and
+// others with fmt.Sprint.
+func appendValue(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return appendNil(b)
+	case bool:
+		return appendBool(b, v)
+	case int:
+		return appendInt(b, int64(v))
+	case int8:
+		return appendInt(b, int64(v))
+	case int16:
+		return appendInt(b, int64(v))
+	case int32:
+		return appendInt(b, int64(v))
+	case int64:
+		return appendInt(b, v)
+	case uint:
+		return appendUint(b, uint64(v))
+	case uint8:
+		return appendUint(b, uint64(v))
+	case uint16:
+		return appendUint(b, uint64(v))
+	case uint32:
+		return appendUint(b, uint64(v))
+	case uint64:
+		return appendUint(b, v)
+	case float32:
+		return appendFloat32(b, v)
+	case float64:
+		return appendFloat64(b, v)
+	case string:
+		return appendString(b, v)
+	case []byte:
+		return appendBinary(b, v)
+	case time.Time:
+		return appendString(b, v.Format(time.RFC3339Nano))
+	case time.Duration:
+		return appendString(b, v.String())
+	case error:
+		return appendString(b, v.Error())
+	case []interface{}:
+		b = appendArrayHeader(b, len(v))
+		for _, e := range v {
+			b = appendValue(b, e)
+		}
+		return b
+	case []string:
+		b = appendArrayHeader(b, len(v))
+		for _, e := range v {
+			b = appendString(b, e)
+		}
+		return b
+	case mlog.Map:
+		return appendMap(b, v)
+	case map[string]interface{}:
+		return appendMap(b, v)
+	default:
+		return appendString(b, fmt.Sprint(v))
+	}
+}
+
+func appendMap(b []byte, m map[string]interface{}) []byte {
+	b = appendMapHeader(b, len(m))
+	for k, v := range m {
+		b = appendString(b, k)
+		b = appendValue(b, v)
+	}
+	return b
+}
+
+var errInvalidMsgpack = errors.New("fluent: invalid msgpack")
+
+// maxDecodeLen is the maximum length of a decoded string, binary value,
+// array or map, and arrays and maps are grown as they are decoded, rather
+// than allocated up front. Only acks are decoded, which are much smaller, so
+// this bounds what a misbehaving server can make the Emitter allocate.
+const maxDecodeLen = 1 << 20
+
+// maxDecodeDepth is the maximum nesting depth of decoded arrays and maps.
+// Acks are a map of strings, one level deep.
+const maxDecodeDepth = 8
+
+// ext is a decoded MessagePack extension value.
+type ext struct {
+	typ  int8
+	data []byte
+}
+
+// decodeValue decodes a single MessagePack value from r. Maps are decoded as
+// map[string]interface{}, and must have string keys. Integers are decoded as
+// int64 or uint64, and strings and binary values as string. Arrays and maps
+// nested more than maxDecodeDepth levels deep are invalid.
+func decodeValue(r *bufio.Reader) (interface{}, error) {
+	return decodeDepth(r, 0)
+}
+
+// decodeDepth decodes a single MessagePack value nested depth levels deep.
+func decodeDepth(r *bufio.Reader, depth int) (interface{}, error) {
+	if depth > maxDecodeDepth {
+		return nil, errInvalidMsgpack
+	}
+
+	c, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c <= 0x7f:
+		return uint64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return decodeMap(r, int(c&0x0f), depth)
+	case c&0xf0 == 0x90:
+		return decodeArray(r, int(c&0x0f), depth)
+	case c&0xe0 == 0xa0:
+		return decodeString(r, int(c&0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xd9:
+		n, err := readUint(r, 1)
+		if err != nil {
+			return nil, err
+		}
+		return decodeString(r, int(n))
+	case 0xc5, 0xda:
+		n, err := readUint(r, 2)
+		if err != nil {
+			return nil, err
+		}
+		return decodeString(r, int(n))
+	case 0xc6, 0xdb:
+		n, err := readUint(r, 4)
+		if err != nil {
+			return nil, err
+		}
+		return decodeString(r, int(n))
+	case 0xca:
+		n, err := readUint(r, 4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := readUint(r, 8)
+		return math.Float64frombits(n), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return readUint(r, 1<<(c-0xcc))
+	case 0xd0:
+		n, err := readUint(r, 1)
+		return int64(int8(n)), err
+	case 0xd1:
+		n, err := readUint(r, 2)
+		return int64(int16(n)), err
+	case 0xd2:
+		n, err := readUint(r, 4)
+		return int64(int32(n)), err
+	case 0xd3:
+		n, err := readUint(r, 8)
+		return int64(n), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return decodeExt(r, 1<<(c-0xd4))
+	case 0xdc:
+		n, err := readUint(r, 2)
+		if err != nil {
+			return nil, err
+		}
+		return decodeArray(r, int(n), depth)
+	case 0xdd:
+		n, err := readUint(r, 4)
+		if err != nil {
+			return nil, err
+		}
+		return decodeArray(r, int(n), depth)
+	case 0xde:
+		n, err := readUint(r, 2)
+		if err != nil {
+			return nil, err
+		}
+		return decodeMap(r, int(n), depth)
+	case 0xdf:
+		n, err := readUint(r, 4)
+		if err != nil {
+			return nil, err
+		}
+		return decodeMap(r, int(n), depth)
+	default:
+		return nil, errInvalidMsgpack
+	}
+}
+
+// readUint reads a big endian unsigned integer of size bytes.
+func readUint(r *bufio.Reader, size int) (uint64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(r, buf[8-size:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buf[:]), nil
+}
+
+func decodeString(r *bufio.Reader, n int) (string, error) {
+	if n > maxDecodeLen {
+		return "", errInvalidMsgpack
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func decodeExt(r *bufio.Reader, n int) (ext, error) {
+	if n > maxDecodeLen {
+		return ext{}, errInvalidMsgpack
+	}
+	typ, err := r.ReadByte()
+	if err != nil {
+		return ext{}, err
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return ext{}, err
+	}
+	return ext{typ: int8(typ), data: data}, nil
+}
+
+func decodeArray(r *bufio.Reader, n, depth int) ([]interface{}, error) {
+	if n > maxDecodeLen {
+		return nil, errInvalidMsgpack
+	}
+	a := make([]interface{}, 0, min(n, 16))
+	for i := 0; i < n; i++ {
+		v, err := decodeDepth(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+	}
+	return a, nil
+}
+
+func decodeMap(r *bufio.Reader, n, depth int) (map[string]interface{}, error) {
+	if n > maxDecodeLen {
+		return nil, errInvalidMsgpack
+	}
+	m := make(map[string]interface{}, min(n, 16))
+	for i := 0; i < n; i++ {
+		k, err := decodeDepth(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			return nil, errInvalidMsgpack
+		}
+		v, err := decodeDepth(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	return m, nil
+}
@@ -0,0 +1,105 @@
+package fluent
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cactus/mlog"
+	"github.com/dropwhile/assert"
+)
+
+func decodeBytes(t *testing.T, b []byte) interface{} {
+	t.Helper()
+	v, err := decodeValue(bufio.NewReader(bytes.NewReader(b)))
+	assert.Nil(t, err)
+	return v
+}
+
+func TestMsgpackEncoding(t *testing.T) {
+	var tests = []struct {
+		input interface{}
+		want  []byte
+	}{
+		{nil, []byte{0xc0}},
+		{true, []byte{0xc3}},
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{128, []byte{0xcc, 0x80}},
+		{-1, []byte{0xff}},
+		{-32, []byte{0xe0}},
+		{-33, []byte{0xd0, 0xdf}},
+		{256, []byte{0xcd, 0x01, 0x00}},
+		{int64(-1 << 40), []byte{0xd3, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{uint64(1 << 32), []byte{0xcf, 0, 0, 0, 1, 0, 0, 0, 0}},
+		{1.5, []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{"abc", []byte{0xa3, 'a', 'b', 'c'}},
+		{[]byte{1}, []byte{0xc4, 0x01, 0x01}},
+		{[]interface{}{1, "a"}, []byte{0x92, 0x01, 0xa1, 'a'}},
+		{mlog.Map{"a": 1}, []byte{0x81, 0xa1, 'a', 0x01}},
+		{time.Second, []byte{0xa2, '1', 's'}},
+		{errors.New("x"), []byte{0xa1, 'x'}},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, appendValue(nil, tc.input), tc.want)
+	}
+}
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	var tests = []struct {
+		input interface{}
+		want  interface{}
+	}{
+		{nil, nil},
+		{false, false},
+		{100, uint64(100)},
+		{70000, uint64(70000)},
+		{uint64(math.MaxUint64), uint64(math.MaxUint64)},
+		{-100, int64(-100)},
+		{-1000, int64(-1000)},
+		{-100000, int64(-100000)},
+		{int64(math.MinInt64), int64(math.MinInt64)},
+		{float32(0.5), 0.5},
+		{math.Pi, math.Pi},
+		{strings.Repeat("x", 40), strings.Repeat("x", 40)},
+		{strings.Repeat("x", 300), strings.Repeat("x", 300)},
+		{strings.Repeat("x", 70000), strings.Repeat("x", 70000)},
+		{[]string{"a", "b"}, []interface{}{"a", "b"}},
+		{map[string]interface{}{"k": -1}, map[string]interface{}{"k": int64(-1)}},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, decodeBytes(t, appendValue(nil, tc.input)), tc.want)
+	}
+
+	big := make([]interface{}, 20)
+	for i := range big {
+		big[i] = uint64(i)
+	}
+	assert.Equal(t, decodeBytes(t, appendValue(nil, big)), interface{}(big))
+
+	tm := time.Date(2016, time.January, 11, 12, 13, 14, 15, time.UTC)
+	assert.Equal(t, decodeBytes(t, appendEventTime(nil, tm)),
+		interface{}(ext{typ: eventTimeExt, data: []byte{0x56, 0x93, 0x9c, 0x5a, 0, 0, 0, 15}}))
+}
+
+func TestMsgpackDecodeLimits(t *testing.T) {
+	var tests = [][]byte{
+		{0xdb, 0xff, 0xff, 0xff, 0xff},
+		{0xc6, 0x00, 0x10, 0x00, 0x01},
+		{0xdd, 0xff, 0xff, 0xff, 0xff},
+		{0xdf, 0x00, 0x10, 0x00, 0x01},
+		// arrays nested too deep
+		append(bytes.Repeat([]byte{0x91}, 100000), 0xc0),
+	}
+
+	for _, b := range tests {
+		_, err := decodeValue(bufio.NewReader(bytes.NewReader(b)))
+		assert.Error(t, err, errInvalidMsgpack)
+	}
+}
@@ -1,8 +1,11 @@
 package mlog
 
 import (
+	"encoding"
+	"encoding/json"
 	"fmt"
-	"runtime"
+	"math"
+	"reflect"
 	"time"
 	"unicode/utf8"
 )
@@ -15,56 +18,42 @@ const hex = "0123456789abcdef"
 //	{"time": "2016-04-29T20:49:12Z", "level": "I", "msg": "this is a log"}
 type FormatWriterJSON struct{}
 
-// Emit constructs and formats a json log line (with optional extra Attrs), then writes it to logger
+// EmitAttrs constructs and formats a json log line (with optional extra Attrs), then writes it to logger
 func (j *FormatWriterJSON) EmitAttrs(logger *Logger, level int, message string, extra ...*Attr) {
+	EmitAttrsRecord(j, logger, level, message, extra)
+}
+
+// Emit constructs and formats a json log line (with nillable extra Map), then writes it to logger
+func (j *FormatWriterJSON) Emit(logger *Logger, level int, message string, extra Map) {
+	EmitMapRecord(j, logger, level, message, extra)
+}
+
+// EmitRecord formats r as a json log line, then writes it to logger
+func (j *FormatWriterJSON) EmitRecord(logger *Logger, r Record) {
 	sb := bufPool.Get()
 	defer bufPool.Put(sb)
 
 	flags := logger.Flags()
 
 	sb.WriteByte('{')
-	// if time is being logged, handle time as soon as possible
-	if flags&(Ltimestamp|Ltai64n) != 0 {
-		t := time.Now()
+	if flags&(Ltimestamp|Ltai64n) != 0 && !r.Time.IsZero() {
 		sb.WriteString(`"time": "`)
 		if flags&Ltai64n != 0 {
-			writeTimeTAI64N(sb, &t)
+			writeTimeTAI64N(sb, &r.Time)
 		} else {
-			writeTime(sb, &t)
+			writeTime(sb, &r.Time)
 		}
 		sb.WriteString(`", `)
 	}
 
 	if flags&Llevel != 0 {
 		sb.WriteString(`"level": "`)
-		switch level {
-		case -1:
-			sb.WriteByte('D')
-		case 1:
-			sb.WriteByte('F')
-		default:
-			sb.WriteByte('I')
-		}
+		sb.WriteByte(r.Level.letter())
 		sb.WriteString(`", `)
 	}
 
-	if flags&(Lshortfile|Llongfile) != 0 {
-		_, file, line, ok := runtime.Caller(3)
-		if !ok {
-			file = "???"
-			line = 0
-		}
-
-		if flags&Lshortfile != 0 {
-			short := file
-			for i := len(file) - 1; i > 0; i-- {
-				if file[i] == '/' {
-					short = file[i+1:]
-					break
-				}
-			}
-			file = short
-		}
+	if flags&(Lshortfile|Llongfile) != 0 && r.PC != 0 {
+		file, line := callerFileLine(r.PC, flags&Lshortfile != 0)
 
 		sb.WriteString(`"caller": "`)
 		sb.WriteString(file)
@@ -74,15 +63,21 @@ func (j *FormatWriterJSON) EmitAttrs(logger *Logger, level int, message string,
 	}
 
 	sb.WriteString(`"msg": "`)
-	encodeStringJSON(sb, message)
+	encodeStringJSON(sb, r.Message)
 	sb.WriteByte('"')
 
-	if len(extra) > 0 {
-		attrs := filterAttrs(extra)
-		if len(attrs) > 0 {
-			sb.WriteString(`, "extra": `)
-			encodeLogAttrsJSON(sb, attrs)
+	fields := logger.boundFields(j)
+	nattrs := r.NumAttrs()
+	if len(fields) > 0 || nattrs > 0 {
+		sb.WriteString(`, "extra": {`)
+		sb.Write(fields)
+		for i := 0; i < nattrs; i++ {
+			if i > 0 || len(fields) > 0 {
+				sb.WriteString(`, `)
+			}
+			encodeAttrJSON(sb, r.attr(i))
 		}
+		sb.WriteByte('}')
 	}
 
 	sb.WriteByte('}')
@@ -90,111 +85,205 @@ func (j *FormatWriterJSON) EmitAttrs(logger *Logger, level int, message string,
 	sb.WriteTo(logger)
 }
 
-// Emit constructs and formats a json log line (with nillable extra Map), then writes it to logger
-func (j *FormatWriterJSON) Emit(logger *Logger, level int, message string, extra Map) {
-	sb := bufPool.Get()
-	defer bufPool.Put(sb)
-
-	flags := logger.Flags()
+// encodeFields pre-encodes the Attrs bound to a Logger with With.
+func (j *FormatWriterJSON) encodeFields(w byteSliceWriter, attrs []*Attr) {
+	encodeLogAttrsJSON(w, attrs)
+}
 
-	sb.WriteByte('{')
-	// if time is being logged, handle time as soon as possible
-	if flags&(Ltimestamp|Ltai64n) != 0 {
-		t := time.Now()
-		sb.WriteString(`"time": "`)
-		if flags&Ltai64n != 0 {
-			writeTimeTAI64N(sb, &t)
-		} else {
-			writeTime(sb, &t)
+// encodeLogAttrsJSON writes attrs as json object members, without the
+// enclosing braces.
+func encodeLogAttrsJSON(w byteSliceWriter, attrs []*Attr) {
+	attrsLen := len(attrs)
+	for i, attr := range attrs {
+		encodeAttrJSON(w, attr)
+		if i != attrsLen-1 {
+			w.WriteString(`, `)
 		}
-		sb.WriteString(`", `)
 	}
+}
 
-	if flags&Llevel != 0 {
-		sb.WriteString(`"level": "`)
-		switch level {
-		case -1:
-			sb.WriteByte('D')
-		case 1:
-			sb.WriteByte('F')
+// encodeAttrJSON writes attr as a json object member.
+func encodeAttrJSON(w byteSliceWriter, attr *Attr) {
+	w.WriteByte('"')
+	encodeStringJSON(w, attr.Key)
+	w.WriteString(`": `)
+	encodeAttrValueJSON(w, attr)
+}
+
+// encodeAttrValueJSON writes the value of attr as a json value.
+func encodeAttrValueJSON(w byteSliceWriter, attr *Attr) {
+	switch attr.kind {
+	case kindString:
+		w.WriteByte('"')
+		encodeStringJSON(w, attr.str)
+		w.WriteByte('"')
+	case kindInt64:
+		writeInt(w, int64(attr.num))
+	case kindUint64:
+		writeUint(w, attr.num)
+	case kindFloat64:
+		encodeFloatJSON(w, math.Float64frombits(attr.num), 64)
+	case kindBool:
+		writeBool(w, attr.num == 1)
+	case kindDuration:
+		w.WriteByte('"')
+		writeDuration(w, time.Duration(attr.num))
+		w.WriteByte('"')
+	case kindTime:
+		w.WriteByte('"')
+		writeTimeRFC3339(w, attr.time())
+		w.WriteByte('"')
+	default:
+		switch v := attr.Value.(type) {
+		case time.Duration:
+			w.WriteByte('"')
+			writeDuration(w, v)
+			w.WriteByte('"')
+		case time.Time:
+			w.WriteByte('"')
+			writeTimeRFC3339(w, v)
+			w.WriteByte('"')
 		default:
-			sb.WriteByte('I')
+			encodeValueJSON(w, attr.Value)
 		}
-		sb.WriteString(`", `)
 	}
+}
 
-	if flags&(Lshortfile|Llongfile) != 0 {
-		_, file, line, ok := runtime.Caller(3)
-		if !ok {
-			file = "???"
-			line = 0
+// encodeValueJSON writes v as a native json value where possible: numbers,
+// booleans and null for basic types, and arrays and objects for slices, maps
+// and structs. Values implementing json.Marshaler or encoding.TextMarshaler
+// are encoded with those. Errors, fmt.Stringers (other than slices and maps)
+// and values that have no json representation are written as a string.
+func encodeValueJSON(w byteSliceWriter, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		w.WriteString(`null`)
+		return
+	case string:
+		w.WriteByte('"')
+		encodeStringJSON(w, v)
+		w.WriteByte('"')
+		return
+	case bool:
+		if v {
+			w.WriteString(`true`)
+		} else {
+			w.WriteString(`false`)
 		}
-
-		if flags&Lshortfile != 0 {
-			short := file
-			for i := len(file) - 1; i > 0; i-- {
-				if file[i] == '/' {
-					short = file[i+1:]
-					break
-				}
+		return
+	case int:
+		writeInt(w, int64(v))
+		return
+	case int8:
+		writeInt(w, int64(v))
+		return
+	case int16:
+		writeInt(w, int64(v))
+		return
+	case int32:
+		writeInt(w, int64(v))
+		return
+	case int64:
+		writeInt(w, v)
+		return
+	case uint:
+		writeUint(w, uint64(v))
+		return
+	case uint8:
+		writeUint(w, uint64(v))
+		return
+	case uint16:
+		writeUint(w, uint64(v))
+		return
+	case uint32:
+		writeUint(w, uint64(v))
+		return
+	case uint64:
+		writeUint(w, v)
+		return
+	case float32:
+		encodeFloatJSON(w, float64(v), 32)
+		return
+	case float64:
+		encodeFloatJSON(w, v, 64)
+		return
+	case json.Marshaler, encoding.TextMarshaler:
+		if b, err := json.Marshal(v); err == nil {
+			w.Write(b)
+			return
+		}
+	case error:
+		w.WriteByte('"')
+		encodeStringJSON(w, v.Error())
+		w.WriteByte('"')
+		return
+	default:
+		kind := reflect.ValueOf(v).Kind()
+		switch kind {
+		case reflect.Array, reflect.Slice, reflect.Map:
+			if b, err := json.Marshal(v); err == nil {
+				w.Write(b)
+				return
 			}
-			file = short
 		}
 
-		sb.WriteString(`"caller": "`)
-		sb.WriteString(file)
-		sb.WriteByte(':')
-		sb.AppendIntWidth(line, 0)
-		sb.WriteString(`", `)
-	}
-
-	sb.WriteString(`"msg": "`)
-	encodeStringJSON(sb, message)
-	sb.WriteByte('"')
+		if s, ok := v.(fmt.Stringer); ok {
+			w.WriteByte('"')
+			encodeStringJSON(w, s.String())
+			w.WriteByte('"')
+			return
+		}
 
-	if len(extra) > 0 {
-		sb.WriteString(`, "extra": `)
-		encodeLogMapJSON(sb, extra)
+		switch kind {
+		case reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64, reflect.String,
+			reflect.Struct, reflect.Pointer:
+			if b, err := json.Marshal(v); err == nil {
+				w.Write(b)
+				return
+			}
+		}
 	}
 
-	sb.WriteByte('}')
-	sb.WriteByte('\n')
-	sb.WriteTo(logger)
+	// no better representation, so fall back to a string
+	w.WriteByte('"')
+	encodeStringJSON(w, fmt.Sprint(v))
+	w.WriteByte('"')
 }
 
-func encodeLogMapJSON(w byteSliceWriter, m Map) {
-	first := true
-	w.WriteByte('{')
-	for k, v := range m {
-		if first {
-			first = false
-		} else {
-			w.WriteString(`, `)
-		}
-
+// encodeFloatJSON writes f as a json number. NaN and infinities have no
+// json representation, so are written as a string.
+func encodeFloatJSON(w byteSliceWriter, f float64, bitSize int) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
 		w.WriteByte('"')
-		encodeStringJSON(w, k)
-		w.WriteString(`": "`)
-		encodeStringJSON(w, fmt.Sprint(v))
+		writeFloat(w, f, bitSize)
 		w.WriteByte('"')
+		return
 	}
-	w.WriteByte('}')
+	writeFloat(w, f, bitSize)
 }
 
-func encodeLogAttrsJSON(w byteSliceWriter, attrs []*Attr) {
-	w.WriteByte('{')
-	attrsLen := len(attrs)
-	for i, attr := range attrs {
-		w.WriteByte('"')
-		encodeStringJSON(w, attr.Key)
-		w.WriteString(`": "`)
-		encodeStringJSON(w, fmt.Sprint(attr.Value))
-		w.WriteByte('"')
-		if i != attrsLen-1 {
-			w.WriteString(`, `)
-		}
-	}
-	w.WriteByte('}')
+// AppendJSONValue appends the value of attr to b as a json value, encoded as
+// by FormatWriterJSON, and returns the extended buffer. Numbers, booleans and
+// nil are written as native json values, slices, maps and structs as json
+// arrays and objects, and other values as json strings.
+func AppendJSONValue(b []byte, attr *Attr) []byte {
+	sb := &sliceBuffer{b}
+	encodeAttrValueJSON(sb, attr)
+	return sb.Bytes()
+}
+
+// AppendJSONString appends s to b as a quoted json string, escaped as by
+// FormatWriterJSON, and returns the extended buffer. Invalid UTF-8 is
+// replaced by U+FFFD.
+func AppendJSONString(b []byte, s string) []byte {
+	sb := &sliceBuffer{b}
+	sb.WriteByte('"')
+	encodeStringJSON(sb, s)
+	sb.WriteByte('"')
+	return sb.Bytes()
 }
 
 // modified from Go stdlib: encoding/json/encode.go:787-862 (approx)
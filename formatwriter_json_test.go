@@ -7,9 +7,12 @@ package mlog
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"testing"
+	"time"
 
 	"github.com/dropwhile/assert"
 )
@@ -38,14 +41,115 @@ func TestFormatWriterJSONEncodeStringMap(t *testing.T) {
 	}
 }
 
+func TestAppendJSONString(t *testing.T) {
+	for name, s := range jsonStringTests {
+		e, err := json.Marshal(s)
+		assert.Nil(t, err, fmt.Sprintf("%s: json marshal failed", name))
+		var want, got string
+		assert.Nil(t, json.Unmarshal(e, &want))
+
+		b := AppendJSONString([]byte("x"), s)
+		assert.Equal(t, b[0], 'x', fmt.Sprintf("%s: prefix not kept", name))
+		assert.Nil(t, json.Unmarshal(b[1:], &got), fmt.Sprintf("%s: invalid json", name))
+		assert.Equal(t, got, want, fmt.Sprintf("%s: did not match expectation", name))
+	}
+}
+
+func TestAppendJSONValue(t *testing.T) {
+	var cases = []struct {
+		attr *Attr
+		want string
+	}{
+		{String("k", "a\"b"), `"a\"b"`},
+		{Int64("k", -1), `-1`},
+		{Float64("k", math.Inf(1)), `"+Inf"`},
+		{Bool("k", true), `true`},
+		{Duration("k", time.Second), `"1s"`},
+		{Err("k", nil), `null`},
+		{A("k", nil), `null`},
+		{A("k", []int{1, 2}), `[1,2]`},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, string(AppendJSONValue([]byte("x"), tc.attr)), "x"+tc.want)
+	}
+}
+
 func TestFormatWriterJSONAttrsNil(t *testing.T) {
 	logger := New(io.Discard, 0)
 	logWriter := &FormatWriterJSON{}
 	attrs := make([]*Attr, 10)
 	for i := 1; i <= 5; i++ {
-		attrs = append(attrs, &Attr{randString(6, false), randString(10, false)})
+		attrs = append(attrs, &Attr{Key: randString(6, false), Value: randString(10, false)})
 	}
 	for i := 0; i < len(attrs); i++ {
 		logWriter.EmitAttrs(logger, 0, "this is a test", attrs...)
 	}
 }
+
+type testJSONText struct{}
+
+func (testJSONText) MarshalText() ([]byte, error) { return []byte("text"), nil }
+
+type testJSONStringer int
+
+func (testJSONStringer) String() string { return "stringer" }
+
+type testJSONStruct struct {
+	A int    `json:"a"`
+	B string `json:"b"`
+}
+
+func TestFormatWriterJSONEncodeValue(t *testing.T) {
+	var nilPtr *testJSONStruct
+	valueTests := map[string]struct {
+		input  interface{}
+		output string
+	}{
+		"nil":         {nil, `null`},
+		"string":      {"te\"st", `"te\"st"`},
+		"bool":        {false, `false`},
+		"int":         {42, `42`},
+		"int8":        {int8(-8), `-8`},
+		"uint64":      {uint64(1 << 63), `9223372036854775808`},
+		"float64":     {1.5, `1.5`},
+		"float32":     {float32(0.1), `0.1`},
+		"nan":         {math.NaN(), `"NaN"`},
+		"named int":   {testJSONStringer(0), `"stringer"`},
+		"flagset":     {Llevel, `"Llevel"`},
+		"slice":       {[]int{1, 2}, `[1,2]`},
+		"map":         {Map{"x": 1, "y": []string{"z"}}, `{"x":1,"y":["z"]}`},
+		"struct":      {testJSONStruct{1, "c"}, `{"a":1,"b":"c"}`},
+		"nil pointer": {nilPtr, `null`},
+		"marshaler":   {json.RawMessage(`{"raw": true}`), `{"raw":true}`},
+		"text":        {testJSONText{}, `"text"`},
+		"time":        {time.Date(2016, time.January, 11, 12, 13, 14, 15, time.UTC), `"2016-01-11T12:13:14.000000015Z"`},
+		"error":       {errors.New("oops"), `"oops"`},
+		"duration":    {time.Second, `"1s"`},
+		"chan":        {make(chan int), ``},
+	}
+
+	b := &bytes.Buffer{}
+	for name, tt := range valueTests {
+		b.Truncate(0)
+		encodeValueJSON(b, tt.input)
+		if tt.output == "" {
+			// no json representation, so should be a string
+			assert.MatchesRegex(t, b.String(), `^"0x[0-9a-f]+"$`, fmt.Sprintf("%s: did not match expectation", name))
+			continue
+		}
+		assert.Equal(t, b.String(), tt.output, fmt.Sprintf("%s: did not match expectation", name))
+	}
+}
+
+func TestFormatWriterJSONTypedExtra(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewFormatLogger(buf, Lsort, &FormatWriterJSON{})
+	logger.Infom("test", Map{"count": 42, "ok": false, "none": nil})
+
+	var m map[string]interface{}
+	err := json.Unmarshal(buf.Bytes(), &m)
+	assert.Nil(t, err)
+	assert.Equal(t, m["extra"], interface{}(map[string]interface{}{
+		"count": float64(42), "ok": false, "none": nil,
+	}))
+}
@@ -1,8 +1,6 @@
 package mlog
 
 import (
-	"runtime"
-	"time"
 	"unicode/utf8"
 )
 
@@ -12,116 +10,43 @@ import (
 //	2016-04-29T20:49:12Z INFO this is a log
 type FormatWriterPlain struct{}
 
-// Emit constructs and formats a plain text log line (with optional extra Attrs), then writes it to logger
+// EmitAttrs constructs and formats a plain text log line (with optional extra Attrs), then writes it to logger
 func (l *FormatWriterPlain) EmitAttrs(logger *Logger, level int, message string, extra ...*Attr) {
-	sb := bufPool.Get()
-	defer bufPool.Put(sb)
-
-	flags := logger.Flags()
-
-	// if time is being logged, handle time as soon as possible
-	if flags&(Ltimestamp|Ltai64n) != 0 {
-		t := time.Now()
-		if flags&Ltai64n != 0 {
-			writeTimeTAI64N(sb, &t)
-		} else {
-			writeTime(sb, &t)
-		}
-		sb.WriteByte(' ')
-	}
-
-	if flags&Llevel != 0 {
-		switch level {
-		case -1:
-			sb.WriteString(`DEBUG `)
-		case 1:
-			sb.WriteString(`FATAL `)
-		default:
-			sb.WriteString(`INFO  `)
-		}
-	}
-
-	if flags&(Lshortfile|Llongfile) != 0 {
-		_, file, line, ok := runtime.Caller(3)
-		if !ok {
-			file = "???"
-			line = 0
-		}
-
-		if flags&Lshortfile != 0 {
-			short := file
-			for i := len(file) - 1; i > 0; i-- {
-				if file[i] == '/' {
-					short = file[i+1:]
-					break
-				}
-			}
-			file = short
-		}
-
-		sb.WriteString(file)
-		sb.WriteByte(':')
-		sb.AppendIntWidth(line, 0)
-		sb.WriteByte(' ')
-	}
-
-	encodeStringPlain(sb, message)
-
-	if len(extra) > 0 {
-		sb.WriteByte(' ')
-		attrsWriteBuf(sb, extra)
-	}
-
-	sb.WriteByte('\n')
-	sb.WriteTo(logger)
+	EmitAttrsRecord(l, logger, level, message, extra)
 }
 
 // Emit constructs and formats a plain text log line (with nillable extra Map), then writes it to logger
 func (l *FormatWriterPlain) Emit(logger *Logger, level int, message string, extra Map) {
+	EmitMapRecord(l, logger, level, message, extra)
+}
+
+// EmitRecord formats r as a plain text log line, then writes it to logger
+func (l *FormatWriterPlain) EmitRecord(logger *Logger, r Record) {
 	sb := bufPool.Get()
 	defer bufPool.Put(sb)
 
 	flags := logger.Flags()
 
-	// if time is being logged, handle time as soon as possible
-	if flags&(Ltimestamp|Ltai64n) != 0 {
-		t := time.Now()
+	if flags&(Ltimestamp|Ltai64n) != 0 && !r.Time.IsZero() {
 		if flags&Ltai64n != 0 {
-			writeTimeTAI64N(sb, &t)
+			writeTimeTAI64N(sb, &r.Time)
 		} else {
-			writeTime(sb, &t)
+			writeTime(sb, &r.Time)
 		}
 		sb.WriteByte(' ')
 	}
 
 	if flags&Llevel != 0 {
-		switch level {
-		case -1:
-			sb.WriteString(`DEBUG `)
-		case 1:
-			sb.WriteString(`FATAL `)
-		default:
-			sb.WriteString(`INFO  `)
+		// pad to the longest level name, so messages line up
+		name := r.Level.String()
+		sb.WriteString(name)
+		for i := len(name); i <= 5; i++ {
+			sb.WriteByte(' ')
 		}
 	}
 
-	if flags&(Lshortfile|Llongfile) != 0 {
-		_, file, line, ok := runtime.Caller(3)
-		if !ok {
-			file = "???"
-			line = 0
-		}
-
-		if flags&Lshortfile != 0 {
-			short := file
-			for i := len(file) - 1; i > 0; i-- {
-				if file[i] == '/' {
-					short = file[i+1:]
-					break
-				}
-			}
-			file = short
-		}
+	if flags&(Lshortfile|Llongfile) != 0 && r.PC != 0 {
+		file, line := callerFileLine(r.PC, flags&Lshortfile != 0)
 
 		sb.WriteString(file)
 		sb.WriteByte(':')
@@ -129,21 +54,27 @@ func (l *FormatWriterPlain) Emit(logger *Logger, level int, message string, extr
 		sb.WriteByte(' ')
 	}
 
-	encodeStringPlain(sb, message)
+	encodeStringPlain(sb, r.Message)
 
-	if len(extra) > 0 {
+	if fields := logger.boundFields(l); len(fields) > 0 {
 		sb.WriteByte(' ')
-		if flags&Lsort != 0 {
-			extra.sortedWriteBuf(sb)
-		} else {
-			extra.unsortedWriteBuf(sb)
-		}
+		sb.Write(fields)
+	}
+
+	for i, n := 0, r.NumAttrs(); i < n; i++ {
+		sb.WriteByte(' ')
+		r.attr(i).writeBuf(sb)
 	}
 
 	sb.WriteByte('\n')
 	sb.WriteTo(logger)
 }
 
+// encodeFields pre-encodes the Attrs bound to a Logger with With.
+func (l *FormatWriterPlain) encodeFields(w byteSliceWriter, attrs []*Attr) {
+	attrsWriteBuf(w, attrs)
+}
+
 // modified from Go stdlib: encoding/json/encode.go:787-862 (approx)
 func encodeStringPlain(e byteSliceWriter, s string) {
 	for i := 0; i < len(s); {
@@ -76,7 +76,7 @@ func BenchmarkFormatWriterPlainMap(b *testing.B) {
 func BenchmarkFormatWriterPlainAttrs(b *testing.B) {
 	logger := New(io.Discard, 0)
 	logWriter := &FormatWriterPlain{}
-	attr := Attr{"x", 42}
+	attr := Attr{Key: "x", Value: 42}
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		logWriter.EmitAttrs(logger, 0, "this is a test", &attr)
@@ -114,7 +114,7 @@ func BenchmarkFormatWriterPlainHugeAttrs(b *testing.B) {
 	logWriter := &FormatWriterPlain{}
 	attrs := make([]*Attr, 0, 100)
 	for i := 1; i <= 100; i++ {
-		attrs = append(attrs, &Attr{randString(6, false), randString(10, false)})
+		attrs = append(attrs, &Attr{Key: randString(6, false), Value: randString(10, false)})
 	}
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -1,8 +1,6 @@
 package mlog
 
 import (
-	"runtime"
-	"time"
 	"unicode/utf8"
 )
 
@@ -12,125 +10,41 @@ import (
 //	time="2016-04-29T20:49:12Z" level="I" msg="this is a log"
 type FormatWriterStructured struct{}
 
-// Emit constructs and formats a plain text log line (with optional extra Attrs), then writes it to logger
+// EmitAttrs constructs and formats a plain text log line (with optional extra Attrs), then writes it to logger
 func (l *FormatWriterStructured) EmitAttrs(logger *Logger, level int, message string, extra ...*Attr) {
-	sb := bufPool.Get()
-	defer bufPool.Put(sb)
-
-	flags := logger.Flags()
-
-	// if time is being logged, handle time as soon as possible
-	if flags&(Ltimestamp|Ltai64n) != 0 {
-		t := time.Now()
-		sb.WriteString(`time="`)
-		if flags&Ltai64n != 0 {
-			writeTimeTAI64N(sb, &t)
-		} else {
-			writeTime(sb, &t)
-		}
-		sb.WriteString(`" `)
-	}
-
-	if flags&Llevel != 0 {
-		sb.WriteString(`level="`)
-		switch level {
-		case -1:
-			sb.WriteByte('D')
-		case 1:
-			sb.WriteByte('F')
-		default:
-			sb.WriteByte('I')
-		}
-		sb.WriteString(`" `)
-	}
-
-	if flags&(Lshortfile|Llongfile) != 0 {
-		_, file, line, ok := runtime.Caller(3)
-		if !ok {
-			file = "???"
-			line = 0
-		}
-
-		if flags&Lshortfile != 0 {
-			short := file
-			for i := len(file) - 1; i > 0; i-- {
-				if file[i] == '/' {
-					short = file[i+1:]
-					break
-				}
-			}
-			file = short
-		}
-
-		sb.WriteString(`caller="`)
-		sb.WriteString(file)
-		sb.WriteByte(':')
-		sb.AppendIntWidth(line, 0)
-		sb.WriteString(`" `)
-	}
-
-	sb.WriteString(`msg="`)
-	encodeStringStructured(sb, message)
-	sb.WriteByte('"')
-
-	if len(extra) > 0 {
-		sb.WriteByte(' ')
-		attrsWriteBuf(sb, extra)
-	}
-
-	sb.WriteByte('\n')
-	sb.WriteTo(logger)
+	EmitAttrsRecord(l, logger, level, message, extra)
 }
 
 // Emit constructs and formats a plain text log line (with nillable extra Map), then writes it to logger
 func (l *FormatWriterStructured) Emit(logger *Logger, level int, message string, extra Map) {
+	EmitMapRecord(l, logger, level, message, extra)
+}
+
+// EmitRecord formats r as a plain text log line, then writes it to logger
+func (l *FormatWriterStructured) EmitRecord(logger *Logger, r Record) {
 	sb := bufPool.Get()
 	defer bufPool.Put(sb)
 
 	flags := logger.Flags()
 
-	// if time is being logged, handle time as soon as possible
-	if flags&(Ltimestamp|Ltai64n) != 0 {
-		t := time.Now()
+	if flags&(Ltimestamp|Ltai64n) != 0 && !r.Time.IsZero() {
 		sb.WriteString(`time="`)
 		if flags&Ltai64n != 0 {
-			writeTimeTAI64N(sb, &t)
+			writeTimeTAI64N(sb, &r.Time)
 		} else {
-			writeTime(sb, &t)
+			writeTime(sb, &r.Time)
 		}
 		sb.WriteString(`" `)
 	}
 
 	if flags&Llevel != 0 {
 		sb.WriteString(`level="`)
-		switch level {
-		case -1:
-			sb.WriteByte('D')
-		case 1:
-			sb.WriteByte('F')
-		default:
-			sb.WriteByte('I')
-		}
+		sb.WriteByte(r.Level.letter())
 		sb.WriteString(`" `)
 	}
 
-	if flags&(Lshortfile|Llongfile) != 0 {
-		_, file, line, ok := runtime.Caller(3)
-		if !ok {
-			file = "???"
-			line = 0
-		}
-
-		if flags&Lshortfile != 0 {
-			short := file
-			for i := len(file) - 1; i > 0; i-- {
-				if file[i] == '/' {
-					short = file[i+1:]
-					break
-				}
-			}
-			file = short
-		}
+	if flags&(Lshortfile|Llongfile) != 0 && r.PC != 0 {
+		file, line := callerFileLine(r.PC, flags&Lshortfile != 0)
 
 		sb.WriteString(`caller="`)
 		sb.WriteString(file)
@@ -140,22 +54,28 @@ func (l *FormatWriterStructured) Emit(logger *Logger, level int, message string,
 	}
 
 	sb.WriteString(`msg="`)
-	encodeStringStructured(sb, message)
+	encodeStringStructured(sb, r.Message)
 	sb.WriteByte('"')
 
-	if len(extra) > 0 {
+	if fields := logger.boundFields(l); len(fields) > 0 {
 		sb.WriteByte(' ')
-		if flags&Lsort != 0 {
-			extra.sortedWriteBuf(sb)
-		} else {
-			extra.unsortedWriteBuf(sb)
-		}
+		sb.Write(fields)
+	}
+
+	for i, n := 0, r.NumAttrs(); i < n; i++ {
+		sb.WriteByte(' ')
+		r.attr(i).writeBuf(sb)
 	}
 
 	sb.WriteByte('\n')
 	sb.WriteTo(logger)
 }
 
+// encodeFields pre-encodes the Attrs bound to a Logger with With.
+func (l *FormatWriterStructured) encodeFields(w byteSliceWriter, attrs []*Attr) {
+	attrsWriteBuf(w, attrs)
+}
+
 // modified from Go stdlib: encoding/json/encode.go:787-862 (approx)
 func encodeStringStructured(e byteSliceWriter, s string) {
 	for i := 0; i < len(s); {
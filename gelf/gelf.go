@@ -0,0 +1,307 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Package gelf provides an mlog Emitter that sends log events to Graylog, or
+// any other receiver of GELF 1.1 (Graylog Extended Log Format) messages.
+//
+// Example usage:
+//
+//	e, err := gelf.New(gelf.Options{Address: "graylog.example.com:12201"})
+//	if err != nil {
+//	    // handle error
+//	}
+//	defer e.Close()
+//	logger := mlog.NewFormatLogger(io.Discard, mlog.Lshortfile, e)
+//
+// The message is sent as short_message, and extra Attrs as additional fields,
+// with their keys prefixed by '_'. Attr values are encoded as by
+// mlog.FormatWriterJSON. The caller is sent as the _file and _line fields, if
+// the Logger has the Llongfile or Lshortfile flag. Attrs that would be sent as
+// _id, _file or _line are prefixed with "_attr" instead. The Logger output is
+// not used.
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cactus/mlog"
+	"github.com/cactus/mlog/internal/severity"
+)
+
+// Compression is the compression of GELF messages sent over udp.
+type Compression int
+
+const (
+	// Gzip compresses messages with gzip.
+	Gzip Compression = iota
+	// Zlib compresses messages with zlib.
+	Zlib
+	// NoCompression sends messages uncompressed.
+	NoCompression
+)
+
+// DefaultChunkSize is the default maximum size of a udp datagram, which fits
+// in the MTU of most networks.
+const DefaultChunkSize = 1420
+
+const (
+	// chunkHeaderSize is the size of the header of each chunk: the magic
+	// bytes, message id, sequence number and sequence count.
+	chunkHeaderSize = 12
+	// maxChunks is the maximum number of chunks of a message.
+	maxChunks = 128
+)
+
+// Options configures an Emitter.
+type Options struct {
+	// Network is udp (the default) or tcp.
+	Network string
+	// Address is the address of the GELF input, as with net.Dial.
+	Address string
+	// Compression is the compression used for udp. Defaults to Gzip. Messages
+	// sent over tcp are never compressed.
+	Compression Compression
+	// ChunkSize is the maximum size of a udp datagram. Larger messages are
+	// split into chunks. Defaults to DefaultChunkSize.
+	ChunkSize int
+	// Host is the host field. Defaults to os.Hostname.
+	Host string
+}
+
+// Emitter is an mlog Emitter that sends GELF messages. Over tcp, messages
+// are terminated by a null byte. Over udp, messages are compressed, and
+// chunked if they are larger than the chunk size; messages that would need
+// more than 128 chunks are dropped. If sending fails, the Emitter reconnects
+// in the background, and drops messages until it has reconnected (see
+// mlog.NetWriter). It is safe for concurrent use.
+type Emitter struct {
+	w           *mlog.NetWriter
+	stream      bool
+	compression Compression
+	chunkSize   int
+	host        []byte // json encoded
+}
+
+var bufPool = sync.Pool{
+	New: func() interface{} { return &bytes.Buffer{} },
+}
+
+// compressors are reused with Reset, as each allocates large tables.
+var (
+	gzipPool = sync.Pool{
+		New: func() interface{} { return gzip.NewWriter(nil) },
+	}
+	zlibPool = sync.Pool{
+		New: func() interface{} { return zlib.NewWriter(nil) },
+	}
+)
+
+// New connects to the GELF input described by opts, and returns an Emitter
+// sending to it.
+func New(opts Options) (*Emitter, error) {
+	if opts.Network == "" {
+		opts.Network = "udp"
+	}
+	if opts.ChunkSize <= chunkHeaderSize {
+		opts.ChunkSize = DefaultChunkSize
+	}
+	if opts.Host == "" {
+		opts.Host, _ = os.Hostname()
+	}
+
+	w, err := mlog.NewNetWriter(opts.Network, opts.Address)
+	if err != nil {
+		return nil, err
+	}
+	host := mlog.AppendJSONString(nil, opts.Host)
+	return &Emitter{
+		w:           w,
+		stream:      strings.HasPrefix(opts.Network, "tcp"),
+		compression: opts.Compression,
+		chunkSize:   opts.ChunkSize,
+		host:        host,
+	}, nil
+}
+
+// Close closes the connection to the GELF input.
+func (e *Emitter) Close() error {
+	return e.w.Close()
+}
+
+// Emit sends a log event (with nillable extra Map). A Logger calls EmitRecord
+// instead.
+func (e *Emitter) Emit(logger *mlog.Logger, level int, message string, extra mlog.Map) {
+	mlog.EmitMapRecord(e, logger, level, message, extra)
+}
+
+// EmitAttrs sends a log event (with optional extra Attrs). A Logger calls
+// EmitRecord instead.
+func (e *Emitter) EmitAttrs(logger *mlog.Logger, level int, message string, extra ...*mlog.Attr) {
+	mlog.EmitAttrsRecord(e, logger, level, message, extra)
+}
+
+// EmitRecord sends r as a GELF message.
+func (e *Emitter) EmitRecord(logger *mlog.Logger, r mlog.Record) {
+	b := bufPool.Get().(*bytes.Buffer)
+	defer bufPool.Put(b)
+	b.Reset()
+	e.encode(b, logger, r)
+
+	if e.stream {
+		b.WriteByte(0)
+		_, _ = e.w.Write(b.Bytes())
+		return
+	}
+
+	z := bufPool.Get().(*bytes.Buffer)
+	defer bufPool.Put(z)
+	z.Reset()
+	msg, err := e.compress(z, b.Bytes())
+	if err != nil {
+		return
+	}
+	if len(msg) <= e.chunkSize {
+		_, _ = e.w.Write(msg)
+		return
+	}
+	e.writeChunks(msg)
+}
+
+// encode writes the GELF json message for r to b.
+func (e *Emitter) encode(b *bytes.Buffer, logger *mlog.Logger, r mlog.Record) {
+	b.WriteString(`{"version":"1.1","host":`)
+	b.Write(e.host)
+	b.WriteString(`,"short_message":`)
+	writeString(b, r.Message)
+
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	fmt.Fprintf(b, `,"timestamp":%d.%06d`, t.Unix(), t.Nanosecond()/1000)
+	b.WriteString(`,"level":`)
+	b.WriteString(strconv.Itoa(severity.FromLevel(r.Level)))
+
+	if r.PC != 0 {
+		file, line := r.Caller()
+		if logger != nil && logger.Flags()&mlog.Lshortfile != 0 {
+			file = filepath.Base(file)
+		}
+		b.WriteString(`,"_file":`)
+		writeString(b, file)
+		b.WriteString(`,"_line":`)
+		b.WriteString(strconv.Itoa(line))
+	}
+
+	r.Attrs(func(attr mlog.Attr) bool {
+		b.WriteByte(',')
+		writeString(b, fieldName(attr.Key))
+		b.WriteByte(':')
+		b.Write(mlog.AppendJSONValue(b.AvailableBuffer(), &attr))
+		return true
+	})
+	b.WriteByte('}')
+}
+
+// compressWriter is a gzip or zlib writer.
+type compressWriter interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// compress compresses msg into z, and returns the compressed message.
+func (e *Emitter) compress(z *bytes.Buffer, msg []byte) ([]byte, error) {
+	var pool *sync.Pool
+	switch e.compression {
+	case Gzip:
+		pool = &gzipPool
+	case Zlib:
+		pool = &zlibPool
+	default:
+		return msg, nil
+	}
+
+	zw := pool.Get().(compressWriter)
+	defer pool.Put(zw)
+	zw.Reset(z)
+	if _, err := zw.Write(msg); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return z.Bytes(), nil
+}
+
+// writeChunks sends msg as a sequence of chunks, each with a header of the
+// magic bytes 0x1e 0x0f, a random message id, the sequence number and the
+// sequence count.
+func (e *Emitter) writeChunks(msg []byte) {
+	size := e.chunkSize - chunkHeaderSize
+	count := (len(msg) + size - 1) / size
+	if count > maxChunks {
+		return
+	}
+
+	chunk := make([]byte, 0, e.chunkSize)
+	var id [8]byte
+	binary.BigEndian.PutUint64(id[:], rand.Uint64())
+	for i := 0; i < count; i++ {
+		end := (i + 1) * size
+		if end > len(msg) {
+			end = len(msg)
+		}
+		chunk = append(chunk[:0], 0x1e, 0x0f)
+		chunk = append(chunk, id[:]...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, msg[i*size:end]...)
+		if _, err := e.w.Write(chunk); err != nil {
+			return
+		}
+	}
+}
+
+// reservedFields are the additional fields that Attrs are not sent as: _id,
+// which is reserved by GELF, and the caller fields set by the Emitter.
+var reservedFields = map[string]bool{
+	"_id":   true,
+	"_file": true,
+	"_line": true,
+}
+
+// fieldName returns the GELF additional field name for key: key with a '_'
+// prefix, and characters other than letters, digits, '_', '.' and '-'
+// replaced by '_'. Reserved fields are prefixed with "_attr".
+func fieldName(key string) string {
+	name := "_" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '_', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	if reservedFields[name] {
+		name = "_attr" + name
+	}
+	return name
+}
+
+// writeString writes s to b as a json string.
+func writeString(b *bytes.Buffer, s string) {
+	b.Write(mlog.AppendJSONString(b.AvailableBuffer(), s))
+}
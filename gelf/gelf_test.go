@@ -0,0 +1,164 @@
+package gelf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cactus/mlog"
+	"github.com/dropwhile/assert"
+)
+
+var testTime = time.Date(2016, time.January, 11, 12, 13, 14, 15000, time.UTC)
+
+func listenUDP(t *testing.T) net.PacketConn {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	t.Cleanup(func() { pc.Close() })
+	return pc
+}
+
+func readPacket(t *testing.T, pc net.PacketConn) []byte {
+	t.Helper()
+	buf := make([]byte, 65536)
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	assert.Nil(t, err)
+	return buf[:n]
+}
+
+func decompress(t *testing.T, b []byte, newReader func(io.Reader) (io.ReadCloser, error)) string {
+	t.Helper()
+	zr, err := newReader(bytes.NewReader(b))
+	assert.Nil(t, err)
+	msg, err := io.ReadAll(zr)
+	assert.Nil(t, err)
+	return string(msg)
+}
+
+func gunzip(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) }
+
+func TestEncode(t *testing.T) {
+	e := &Emitter{host: []byte(`"host1"`)}
+	r := mlog.NewRecord(testTime, mlog.LevelWarn, `say "hi"`, 0)
+	r.AddAttrs(
+		mlog.String("user name", "bob"),
+		mlog.Int("n", -1),
+		mlog.Uint64("u", 2),
+		mlog.Float64("f", 1.5),
+		mlog.Float64("nan", math.NaN()),
+		mlog.Bool("ok", true),
+		mlog.Duration("d", time.Second),
+		mlog.Err("error", errors.New("oops")),
+		mlog.A("id", 1),
+		mlog.A("file", "x.go"),
+		mlog.A("nil", nil),
+	)
+
+	b := &bytes.Buffer{}
+	e.encode(b, nil, r)
+	assert.Equal(t, b.String(), `{"version":"1.1","host":"host1","short_message":"say \"hi\"",`+
+		`"timestamp":1452514394.000015,"level":4,"_user_name":"bob","_n":-1,"_u":2,"_f":1.5,`+
+		`"_nan":"NaN","_ok":true,"_d":"1s","_error":"oops","_attr_id":1,"_attr_file":"x.go",`+
+		`"_nil":null}`)
+}
+
+func TestEmitterUDP(t *testing.T) {
+	pc := listenUDP(t)
+	e, err := New(Options{Address: pc.LocalAddr().String(), Host: "host1"})
+	assert.Nil(t, err)
+	defer e.Close()
+
+	logger := mlog.NewFormatLogger(io.Discard, mlog.Lshortfile, e).With(mlog.String("service", "billing"))
+	logger.Info("hello")
+	assert.MatchesRegex(t, decompress(t, readPacket(t, pc), gunzip),
+		`^\{"version":"1.1","host":"host1","short_message":"hello","timestamp":\d+\.\d{6},"level":6,`+
+			`"_file":"gelf_test.go","_line":\d+,"_service":"billing"\}$`)
+	// compressors are reused
+	logger.Info("again")
+	assert.MatchesRegex(t, decompress(t, readPacket(t, pc), gunzip), `"short_message":"again"`)
+
+	e.compression = Zlib
+	logger.Error("zlib")
+	assert.MatchesRegex(t, decompress(t, readPacket(t, pc), zlib.NewReader), `"short_message":"zlib"`)
+	logger.Error("zlib again")
+	assert.MatchesRegex(t, decompress(t, readPacket(t, pc), zlib.NewReader), `"short_message":"zlib again"`)
+
+	e.compression = NoCompression
+	logger.Error("none")
+	assert.MatchesRegex(t, string(readPacket(t, pc)), `^\{.*"short_message":"none".*\}$`)
+}
+
+func TestEmitterUDPChunked(t *testing.T) {
+	pc := listenUDP(t)
+	e, err := New(Options{
+		Address:     pc.LocalAddr().String(),
+		Host:        "host1",
+		Compression: NoCompression,
+		ChunkSize:   100,
+	})
+	assert.Nil(t, err)
+	defer e.Close()
+
+	logger := mlog.NewFormatLogger(io.Discard, 0, e)
+	msg := strings.Repeat("x", 1000)
+	logger.Info(msg)
+
+	var chunks [][]byte
+	for {
+		chunk := readPacket(t, pc)
+		assert.True(t, len(chunk) <= 100)
+		assert.Equal(t, chunk[:2], []byte{0x1e, 0x0f})
+		chunks = append(chunks, append([]byte(nil), chunk...))
+		if len(chunks) == int(chunk[11]) {
+			break
+		}
+	}
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i][10] < chunks[j][10] })
+
+	var full []byte
+	for _, chunk := range chunks {
+		assert.Equal(t, chunk[2:10], chunks[0][2:10], "message id")
+		full = append(full, chunk[12:]...)
+	}
+	assert.MatchesRegex(t, string(full), `^\{.*"short_message":"`+msg+`".*\}$`)
+
+	// too many chunks
+	logger.Info(strings.Repeat("x", 128*88+1))
+	logger.Info("small")
+	assert.MatchesRegex(t, string(readPacket(t, pc)), `"short_message":"small"`)
+}
+
+func TestEmitterTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+
+	e, err := New(Options{Network: "tcp", Address: ln.Addr().String(), Host: "host1"})
+	assert.Nil(t, err)
+	defer e.Close()
+	conn, err := ln.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	logger := mlog.NewFormatLogger(io.Discard, 0, e)
+	logger.Info("one")
+	logger.Info("two")
+
+	br := bufio.NewReader(conn)
+	for _, msg := range []string{"one", "two"} {
+		b, err := br.ReadBytes(0)
+		assert.Nil(t, err)
+		assert.MatchesRegex(t, string(b), `^\{.*"short_message":"`+msg+`".*\}\x00$`)
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Package severity maps mlog Levels onto syslog severities, as used by the
+// syslog, journald and gelf Emitters.
+package severity
+
+import "github.com/cactus/mlog"
+
+// syslog severities
+const (
+	Crit    = 2
+	Err     = 3
+	Warning = 4
+	Info    = 6
+	Debug   = 7
+)
+
+// FromLevel returns the syslog severity for level.
+func FromLevel(level mlog.Level) int {
+	switch {
+	case level < mlog.LevelInfo:
+		return Debug
+	case level < mlog.LevelWarn:
+		return Info
+	case level < mlog.LevelError:
+		return Warning
+	case level < mlog.LevelFatal:
+		return Err
+	default:
+		return Crit
+	}
+}
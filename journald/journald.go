@@ -0,0 +1,191 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Package journald provides an mlog Emitter that sends log events to
+// systemd-journald, using its native protocol, so that extra Attrs become
+// journal fields that can be matched with journalctl, as in:
+//
+//	journalctl ORDER_ID=1234
+//
+// Example usage:
+//
+//	e, err := journald.New(journald.Options{Identifier: "billing"})
+//	if err != nil {
+//	    // handle error
+//	}
+//	defer e.Close()
+//	logger := mlog.NewFormatLogger(io.Discard, mlog.Llongfile, e)
+//
+// Each event is sent with the MESSAGE, PRIORITY and SYSLOG_IDENTIFIER fields,
+// and with CODE_FILE, CODE_LINE and CODE_FUNC if the Logger has the Llongfile
+// or Lshortfile flag. Attr keys are converted to journal field names by
+// upper casing them and replacing invalid characters with '_', so
+// "order.id" becomes ORDER_ID. Attrs whose field name is one of the fields
+// set by the Emitter are prefixed with ATTR_, so that an Attr with the key
+// "message" is sent as ATTR_MESSAGE. The Logger output is not used.
+//
+// The native protocol is only available on linux.
+package journald
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cactus/mlog"
+	"github.com/cactus/mlog/internal/severity"
+)
+
+// DefaultSocket is the path of the journald native protocol socket.
+const DefaultSocket = "/run/systemd/journal/socket"
+
+// maxFieldName is the maximum length of a journal field name.
+const maxFieldName = 64
+
+// reservedFields are the fields set by the Emitter, which Attrs are not sent
+// as.
+var reservedFields = map[string]bool{
+	"MESSAGE":           true,
+	"PRIORITY":          true,
+	"SYSLOG_IDENTIFIER": true,
+	"CODE_FILE":         true,
+	"CODE_LINE":         true,
+	"CODE_FUNC":         true,
+}
+
+// Options configures an Emitter.
+type Options struct {
+	// Socket is the path of the journald socket. Defaults to DefaultSocket.
+	Socket string
+	// Identifier is the SYSLOG_IDENTIFIER field. Defaults to the program
+	// name.
+	Identifier string
+}
+
+// Emitter is an mlog Emitter that sends log events to journald. Entries too
+// large for a datagram are passed to journald in a sealed memfd (or an
+// unlinked temporary file, if memfd is not available). It is safe for
+// concurrent use.
+type Emitter struct {
+	conn       *journalConn
+	identifier string
+}
+
+var bufPool = sync.Pool{
+	New: func() interface{} { return &bytes.Buffer{} },
+}
+
+// New returns an Emitter sending to the journald socket described by opts.
+func New(opts Options) (*Emitter, error) {
+	if opts.Socket == "" {
+		opts.Socket = DefaultSocket
+	}
+	if opts.Identifier == "" {
+		opts.Identifier = filepath.Base(os.Args[0])
+	}
+
+	conn, err := openJournal(opts.Socket)
+	if err != nil {
+		return nil, err
+	}
+	return &Emitter{conn: conn, identifier: opts.Identifier}, nil
+}
+
+// Close closes the connection to journald.
+func (e *Emitter) Close() error {
+	return e.conn.close()
+}
+
+// Emit sends a log event (with nillable extra Map). A Logger calls EmitRecord
+// instead.
+func (e *Emitter) Emit(logger *mlog.Logger, level int, message string, extra mlog.Map) {
+	mlog.EmitMapRecord(e, logger, level, message, extra)
+}
+
+// EmitAttrs sends a log event (with optional extra Attrs). A Logger calls
+// EmitRecord instead.
+func (e *Emitter) EmitAttrs(logger *mlog.Logger, level int, message string, extra ...*mlog.Attr) {
+	mlog.EmitAttrsRecord(e, logger, level, message, extra)
+}
+
+// EmitRecord sends r to journald.
+func (e *Emitter) EmitRecord(logger *mlog.Logger, r mlog.Record) {
+	b := bufPool.Get().(*bytes.Buffer)
+	defer bufPool.Put(b)
+	b.Reset()
+
+	e.encode(b, r)
+	_ = e.conn.send(b.Bytes())
+}
+
+// encode writes the journal entry for r to b.
+func (e *Emitter) encode(b *bytes.Buffer, r mlog.Record) {
+	writeField(b, "MESSAGE", r.Message)
+	writeField(b, "PRIORITY", strconv.Itoa(severity.FromLevel(r.Level)))
+	writeField(b, "SYSLOG_IDENTIFIER", e.identifier)
+	if r.PC != 0 {
+		file, line := r.Caller()
+		writeField(b, "CODE_FILE", file)
+		writeField(b, "CODE_LINE", strconv.Itoa(line))
+		if fn := runtime.FuncForPC(r.PC); fn != nil {
+			writeField(b, "CODE_FUNC", fn.Name())
+		}
+	}
+	r.Attrs(func(attr mlog.Attr) bool {
+		if name := fieldName(attr.Key); name != "" {
+			writeField(b, name, attr.StringValue())
+		}
+		return true
+	})
+}
+
+// writeField writes a journal field. Values containing a newline are written
+// as the field name, a newline, the value length as a little endian uint64,
+// and the value.
+func writeField(b *bytes.Buffer, name, value string) {
+	b.WriteString(name)
+	if strings.IndexByte(value, '\n') < 0 {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+	b.WriteByte('\n')
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	b.Write(size[:])
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
+
+// fieldName returns key as a journal field name: upper case letters, digits
+// and '_', starting with a letter, and at most 64 characters long. Reserved
+// field names are prefixed with ATTR_. It returns an empty string if key has
+// no letters.
+func fieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		default:
+			return '_'
+		}
+	}, key)
+	// fields starting with '_' are trusted fields set by journald
+	name = strings.TrimLeft(name, "_0123456789")
+	if reservedFields[name] {
+		name = "ATTR_" + name
+	}
+	if len(name) > maxFieldName {
+		name = name[:maxFieldName]
+	}
+	return name
+}
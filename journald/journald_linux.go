@@ -0,0 +1,121 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package journald
+
+import (
+	"errors"
+	"net"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// memfd_create system call numbers, which the syscall package does not
+// define for all architectures
+var memfdCreateTrap = map[string]uintptr{
+	"386":     356,
+	"amd64":   319,
+	"arm":     385,
+	"arm64":   279,
+	"ppc64le": 360,
+	"riscv64": 279,
+	"s390x":   350,
+}[runtime.GOARCH]
+
+const (
+	mfdCloexec      = 0x1
+	mfdAllowSealing = 0x2
+	fAddSeals       = 1033
+	// F_SEAL_SEAL | F_SEAL_SHRINK | F_SEAL_GROW | F_SEAL_WRITE
+	allSeals = 0x1 | 0x2 | 0x4 | 0x8
+)
+
+// journalConn is an unconnected datagram socket, so that a journald restart
+// does not require reconnecting.
+type journalConn struct {
+	conn *net.UnixConn
+	addr *net.UnixAddr
+}
+
+func openJournal(path string) (*journalConn, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &journalConn{conn: conn, addr: &net.UnixAddr{Name: path, Net: "unixgram"}}, nil
+}
+
+func (c *journalConn) close() error {
+	return c.conn.Close()
+}
+
+// send sends an entry to journald. An entry too large for a datagram is
+// written to a file, and the file descriptor is sent instead.
+func (c *journalConn) send(entry []byte) error {
+	_, err := c.conn.WriteToUnix(entry, c.addr)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, syscall.EMSGSIZE) && !errors.Is(err, syscall.ENOBUFS) {
+		return err
+	}
+
+	f, err := entryFile(entry)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, _, err = c.conn.WriteMsgUnix(nil, syscall.UnixRights(int(f.Fd())), c.addr)
+	return err
+}
+
+// entryFile returns a sealed memfd holding entry, or if memfd is not
+// available, an unlinked temporary file in /dev/shm.
+func entryFile(entry []byte) (*os.File, error) {
+	if f := memfdCreate(); f != nil {
+		if _, err := f.Write(entry); err != nil {
+			f.Close()
+			return nil, err
+		}
+		_, _, errno := syscall.Syscall(syscall.SYS_FCNTL, f.Fd(), fAddSeals, allSeals)
+		if errno != 0 {
+			f.Close()
+			return nil, errno
+		}
+		return f, nil
+	}
+
+	f, err := os.CreateTemp("/dev/shm", "mlog-journal-")
+	if err != nil {
+		return nil, err
+	}
+	// journald only accepts files that are not linked
+	os.Remove(f.Name())
+	if _, err := f.Write(entry); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// memfdCreate returns a new memfd, or nil if memfd is not available.
+func memfdCreate() *os.File {
+	if memfdCreateTrap == 0 {
+		return nil
+	}
+	name, err := syscall.BytePtrFromString("mlog-journal")
+	if err != nil {
+		return nil
+	}
+	fd, _, errno := syscall.Syscall(memfdCreateTrap, uintptr(unsafe.Pointer(name)), mfdCloexec|mfdAllowSealing, 0)
+	if errno != 0 {
+		return nil
+	}
+	return os.NewFile(fd, "mlog-journal")
+}
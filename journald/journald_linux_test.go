@@ -0,0 +1,73 @@
+package journald
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/cactus/mlog"
+	"github.com/dropwhile/assert"
+)
+
+func listenJournal(t *testing.T) (*net.UnixConn, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	assert.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn, path
+}
+
+// readEntry reads an entry sent to the journal socket, either as a datagram
+// or as a file descriptor.
+func readEntry(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+	buf := make([]byte, 64<<10)
+	oob := make([]byte, syscall.CmsgSpace(4))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	assert.Nil(t, err)
+	if oobn == 0 {
+		return string(buf[:n])
+	}
+
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	assert.Nil(t, err)
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	assert.Nil(t, err)
+	f := os.NewFile(uintptr(fds[0]), "entry")
+	defer f.Close()
+	_, err = f.Seek(0, io.SeekStart)
+	assert.Nil(t, err)
+	b, err := io.ReadAll(f)
+	assert.Nil(t, err)
+	return string(b)
+}
+
+func TestEmitterSocket(t *testing.T) {
+	conn, path := listenJournal(t)
+	e, err := New(Options{Socket: path, Identifier: "app"})
+	assert.Nil(t, err)
+	defer e.Close()
+
+	logger := mlog.NewFormatLogger(io.Discard, 0, e).With(mlog.String("service", "billing"))
+	logger.Infox("hello", mlog.Int("n", 1))
+	assert.Equal(t, readEntry(t, conn),
+		"MESSAGE=hello\nPRIORITY=6\nSYSLOG_IDENTIFIER=app\nSERVICE=billing\nN=1\n")
+
+	// too large for a datagram
+	big := strings.Repeat("x", 1<<20)
+	logger.Info(big)
+	assert.Equal(t, readEntry(t, conn),
+		"MESSAGE="+big+"\nPRIORITY=6\nSYSLOG_IDENTIFIER=app\nSERVICE=billing\n")
+}
+
+func TestEmitterMissingSocket(t *testing.T) {
+	_, err := New(Options{Socket: filepath.Join(t.TempDir(), "missing")})
+	assert.NotNil(t, err)
+}
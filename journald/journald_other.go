@@ -0,0 +1,26 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package journald
+
+import (
+	"errors"
+	"runtime"
+)
+
+type journalConn struct{}
+
+func openJournal(string) (*journalConn, error) {
+	return nil, errors.New("journald: not supported on " + runtime.GOOS)
+}
+
+func (c *journalConn) close() error {
+	return nil
+}
+
+func (c *journalConn) send([]byte) error {
+	return nil
+}
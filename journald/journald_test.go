@@ -0,0 +1,74 @@
+package journald
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cactus/mlog"
+	"github.com/dropwhile/assert"
+)
+
+func TestEncode(t *testing.T) {
+	e := &Emitter{identifier: "app"}
+	r := mlog.NewRecord(time.Now(), mlog.LevelWarn, "hello", 0)
+	r.AddAttrs(
+		mlog.String("order.id", "1234"),
+		mlog.Int("retries", 2),
+		mlog.Err("error", errors.New("two\nlines")),
+		mlog.String("_hostname", "spoofed"),
+		mlog.String("--", "dropped"),
+		mlog.String("message", "not the message"),
+		mlog.String("Priority", "0"),
+		mlog.String("code_file", "other.go"),
+	)
+
+	b := &bytes.Buffer{}
+	e.encode(b, r)
+	assert.Equal(t, b.String(), "MESSAGE=hello\n"+
+		"PRIORITY=4\n"+
+		"SYSLOG_IDENTIFIER=app\n"+
+		"ORDER_ID=1234\n"+
+		"RETRIES=2\n"+
+		"ERROR\n\x09\x00\x00\x00\x00\x00\x00\x00two\nlines\n"+
+		"HOSTNAME=spoofed\n"+
+		"ATTR_MESSAGE=not the message\n"+
+		"ATTR_PRIORITY=0\n"+
+		"ATTR_CODE_FILE=other.go\n")
+}
+
+func TestEncodeCaller(t *testing.T) {
+	var r mlog.Record
+	logger := mlog.NewFormatLogger(nil, mlog.Llongfile, mlog.WrapRecordEmitter(
+		recordFunc(func(_ *mlog.Logger, rec mlog.Record) { r = rec })))
+	logger.Error("test")
+
+	b := &bytes.Buffer{}
+	(&Emitter{identifier: "app"}).encode(b, r)
+	assert.MatchesRegex(t, b.String(), "^MESSAGE=test\nPRIORITY=3\nSYSLOG_IDENTIFIER=app\n"+
+		"CODE_FILE=/.+/journald_test.go\nCODE_LINE=\\d+\nCODE_FUNC=github.com/cactus/mlog/journald.TestEncodeCaller\n$")
+}
+
+type recordFunc func(*mlog.Logger, mlog.Record)
+
+func (f recordFunc) EmitRecord(logger *mlog.Logger, r mlog.Record) { f(logger, r) }
+
+func TestFieldName(t *testing.T) {
+	var tests = []struct {
+		key  string
+		want string
+	}{
+		{"service", "SERVICE"},
+		{"http.status-code", "HTTP_STATUS_CODE"},
+		{"_PID", "PID"},
+		{"2fa", "FA"},
+		{"syslog.identifier", "ATTR_SYSLOG_IDENTIFIER"},
+		{"ünï", "N_"},
+		{"x123456789_123456789_123456789_123456789_123456789_123456789_12345", "X123456789_123456789_123456789_123456789_123456789_123456789_123"},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, fieldName(tc.key), tc.want, tc.key)
+	}
+}
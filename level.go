@@ -0,0 +1,130 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mlog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level is the severity of a log event.
+//
+// The Level values match those of log/slog, so that a Level can be
+// converted to a slog.Level directly. Values between the named Levels are
+// rendered as the next lowest named Level.
+type Level int
+
+// Log levels.
+const (
+	LevelDebug Level = -4
+	LevelInfo  Level = 0
+	LevelWarn  Level = 4
+	LevelError Level = 8
+	LevelFatal Level = 12
+)
+
+// String returns the name of the level, as in "DEBUG" or "WARN".
+func (l Level) String() string {
+	switch {
+	case l < LevelInfo:
+		return "DEBUG"
+	case l < LevelWarn:
+		return "INFO"
+	case l < LevelError:
+		return "WARN"
+	case l < LevelFatal:
+		return "ERROR"
+	default:
+		return "FATAL"
+	}
+}
+
+// ParseLevel parses the name of a Level, as in "debug" or "WARN". Names are
+// case insensitive.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	case "fatal":
+		return LevelFatal, nil
+	default:
+		return 0, fmt.Errorf("mlog: unknown level %q", s)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler, returning the name of the
+// level.
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, parsing text with
+// ParseLevel.
+func (l *Level) UnmarshalText(text []byte) error {
+	level, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
+
+// letter returns the single letter abbreviation of the level, as in 'D' or
+// 'W'.
+func (l Level) letter() byte {
+	return l.String()[0]
+}
+
+// LevelFromEmitter returns the Level of an event passed to the Emit or
+// EmitAttrs method of an Emitter with level: LevelDebug for -1 (or less),
+// LevelInfo for 0, and LevelFatal for 1 (or more).
+func LevelFromEmitter(level int) Level {
+	switch {
+	case level < 0:
+		return LevelDebug
+	case level > 0:
+		return LevelFatal
+	default:
+		return LevelInfo
+	}
+}
+
+// emitterLevel returns the level passed to the Emit and EmitAttrs methods of
+// an Emitter for l: -1 for debug, 1 for fatal, and 0 for the Levels in
+// between.
+func (l Level) emitterLevel() int {
+	switch {
+	case l < LevelInfo:
+		return -1
+	case l < LevelFatal:
+		return 0
+	default:
+		return 1
+	}
+}
+
+// emitterLevelFor returns the level passed to the Emit and EmitAttrs methods
+// of e for l: int(l) if e is a LevelEmitter, and l.emitterLevel() otherwise.
+func (l Level) emitterLevelFor(e Emitter) int {
+	if _, ok := e.(LevelEmitter); ok {
+		return int(l)
+	}
+	return l.emitterLevel()
+}
+
+// levelForEmitter returns the level passed to the Emit and EmitAttrs methods
+// of e for an event logged with Logger.Emit or Logger.EmitAttrs at level.
+func levelForEmitter(e Emitter, level int) int {
+	if _, ok := e.(LevelEmitter); ok {
+		return int(LevelFromEmitter(level))
+	}
+	return level
+}
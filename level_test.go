@@ -0,0 +1,177 @@
+package mlog
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/dropwhile/assert"
+)
+
+func TestLevelString(t *testing.T) {
+	var tests = []struct {
+		level    Level
+		expected string
+	}{
+		{LevelDebug, "DEBUG"},
+		{LevelDebug - 1, "DEBUG"},
+		{LevelInfo, "INFO"},
+		{LevelInfo + 1, "INFO"},
+		{LevelWarn, "WARN"},
+		{LevelError, "ERROR"},
+		{LevelFatal, "FATAL"},
+		{LevelFatal + 1, "FATAL"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.level.String(), tt.expected)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, level := range []Level{LevelDebug, LevelInfo, LevelWarn, LevelError, LevelFatal} {
+		parsed, err := ParseLevel(level.String())
+		assert.Nil(t, err)
+		assert.Equal(t, parsed, level)
+
+		text, err := level.MarshalText()
+		assert.Nil(t, err)
+		var unmarshaled Level
+		assert.Nil(t, unmarshaled.UnmarshalText(text))
+		assert.Equal(t, unmarshaled, level)
+	}
+
+	level, err := ParseLevel(" Warn ")
+	assert.Nil(t, err)
+	assert.Equal(t, level, LevelWarn)
+
+	_, err = ParseLevel("loud")
+	assert.Equal(t, err.Error(), `mlog: unknown level "loud"`)
+	level = LevelError
+	assert.NotNil(t, level.UnmarshalText([]byte("loud")))
+	assert.Equal(t, level, LevelError)
+}
+
+func TestLevelEmitters(t *testing.T) {
+	var tests = []struct {
+		e        Emitter
+		expected string
+	}{
+		{&FormatWriterStructured{}, `level="D" msg="test"
+level="I" msg="test"
+level="W" msg="test"
+level="E" msg="test"
+level="F" msg="test"
+`},
+		{&FormatWriterJSON{}, `{"level": "D", "msg": "test"}
+{"level": "I", "msg": "test"}
+{"level": "W", "msg": "test"}
+{"level": "E", "msg": "test"}
+{"level": "F", "msg": "test"}
+`},
+		{&FormatWriterPlain{}, `DEBUG test
+INFO  test
+WARN  test
+ERROR test
+FATAL test
+`},
+	}
+
+	buf := &bytes.Buffer{}
+	for _, tt := range tests {
+		buf.Truncate(0)
+		logger := NewFormatLogger(buf, Llevel|Ldebug, tt.e)
+		logger.Debugx("test")
+		logger.Infox("test")
+		logger.Warnx("test")
+		logger.Errorx("test")
+		logger.EmitAttrs(1, "test")
+		assert.Equal(t, buf.String(), tt.expected)
+	}
+}
+
+func TestLoggerSetLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(buf, Llevel)
+	assert.Equal(t, logger.Level(), LevelInfo)
+
+	logger.SetLevel(LevelError)
+	logger.Infof("test %d", 1)
+	logger.Warnx("test")
+	logger.Errorm("test", nil)
+	assert.Equal(t, buf.String(), `level="E" msg="test"`+"\n")
+
+	// child loggers share the minimum level
+	buf.Truncate(0)
+	child := logger.With(String("x", "y"))
+	child.Warn("test")
+	logger.SetLevel(LevelWarn)
+	child.Warn("test")
+	assert.Equal(t, buf.String(), `level="W" msg="test" x="y"`+"\n")
+
+	// Ldebug lowers the minimum level to LevelDebug
+	assert.False(t, logger.Enabled(LevelDebug))
+	logger.SetFlags(Llevel | Ldebug)
+	assert.True(t, logger.Enabled(LevelDebug))
+	assert.True(t, logger.Enabled(LevelInfo))
+
+	logger.SetFlags(Llevel)
+	logger.SetLevel(LevelDebug)
+	assert.True(t, logger.Enabled(LevelDebug))
+}
+
+func TestLoggerEnabledLevelFilter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	handler := slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelWarn})
+	logger := NewFormatLogger(io.Discard, 0, NewSlogEmitter(handler))
+
+	assert.False(t, logger.Enabled(LevelInfo))
+	assert.True(t, logger.Enabled(LevelWarn))
+	assert.True(t, logger.Enabled(LevelFatal))
+}
+
+// testLevelEmitter records the levels it is passed.
+type testLevelEmitter struct {
+	levels []int
+}
+
+func (e *testLevelEmitter) Emit(logger *Logger, level int, message string, extra Map) {
+	e.levels = append(e.levels, level)
+}
+
+func (e *testLevelEmitter) EmitAttrs(logger *Logger, level int, message string, extra ...*Attr) {
+	e.levels = append(e.levels, level)
+}
+
+func TestEmitterLevel(t *testing.T) {
+	e := &testLevelEmitter{}
+	logger := NewFormatLogger(io.Discard, Ldebug, e)
+	logger.Debugx("test")
+	logger.Infox("test")
+	logger.Warnm("test", nil)
+	logger.Errorf("test")
+	logger.Emit(1, "test", nil)
+	// Emitters that are not RecordEmitters only see debug, info and fatal
+	assert.Equal(t, e.levels, []int{-1, 0, 0, 0, 1})
+
+	for level, expected := range map[int]Level{-2: LevelDebug, -1: LevelDebug, 0: LevelInfo, 1: LevelFatal, 2: LevelFatal} {
+		assert.Equal(t, LevelFromEmitter(level), expected)
+	}
+}
+
+type testFullLevelEmitter struct {
+	testLevelEmitter
+}
+
+func (e *testFullLevelEmitter) EmitsLevel() {}
+
+func TestLevelEmitter(t *testing.T) {
+	e := &testFullLevelEmitter{}
+	logger := NewFormatLogger(io.Discard, Ldebug, e)
+	logger.Debugx("test")
+	logger.Infox("test")
+	logger.Warnm("test", nil)
+	logger.Errorf("test")
+	logger.Emit(1, "test", nil)
+	assert.Equal(t, e.levels, []int{-4, 0, 4, 8, 12})
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mlog
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+)
+
+// maxWriterLineLength is the maximum length of a line logged by a Writer.
+// Longer lines are split.
+const maxWriterLineLength = 64 * 1024
+
+// Writer returns an io.WriteCloser that logs each line written to it at the
+// given level, along with attrs. This can be used to log the output of an
+// exec.Cmd, or of a library that only accepts an io.Writer.
+//
+// Partial lines are buffered until a newline is written, and lines longer than
+// 64KiB are split. Close logs any trailing partial line. Lines are
+// discarded if the level is not enabled for the Logger.
+func (l *Logger) Writer(level Level, attrs ...*Attr) io.WriteCloser {
+	return &lineWriter{
+		logger: l,
+		level:  level,
+		attrs:  copyAttrs(attrs),
+	}
+}
+
+// lineWriter is the io.WriteCloser returned by Logger.Writer.
+type lineWriter struct {
+	logger *Logger
+	level  Level
+	attrs  []*Attr
+
+	mu     sync.Mutex
+	buf    []byte
+	closed bool
+}
+
+func (w *lineWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrWriterClosed
+	}
+
+	n := len(b)
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			w.buf = append(w.buf, b...)
+			break
+		}
+		w.buf = append(w.buf, b[:i]...)
+		w.emitLine()
+		b = b[i+1:]
+	}
+
+	// log long partial lines now, instead of buffering them
+	if len(w.buf) > maxWriterLineLength {
+		rest := w.buf
+		for len(rest) > maxWriterLineLength {
+			w.emit(rest[:maxWriterLineLength])
+			rest = rest[maxWriterLineLength:]
+		}
+		w.buf = append(w.buf[:0], rest...)
+	}
+	return n, nil
+}
+
+// Close logs any buffered partial line. Writes after Close return
+// ErrWriterClosed.
+func (w *lineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if len(w.buf) > 0 {
+		w.emitLine()
+	}
+	return nil
+}
+
+// emitLine logs the buffered line, and resets the buffer.
+func (w *lineWriter) emitLine() {
+	line := bytes.TrimSuffix(w.buf, []byte{'\r'})
+	for len(line) > maxWriterLineLength {
+		w.emit(line[:maxWriterLineLength])
+		line = line[maxWriterLineLength:]
+	}
+	w.emit(line)
+	w.buf = w.buf[:0]
+}
+
+func (w *lineWriter) emit(line []byte) {
+	if !w.logger.Enabled(w.level) {
+		return
+	}
+	// the caller of Write is not meaningful, so is not logged
+	r := NewRecord(time.Now(), w.level, string(line), 0)
+	r.AddAttrs(w.attrs...)
+	w.logger.EmitRecord(r)
+}
@@ -0,0 +1,63 @@
+package mlog
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dropwhile/assert"
+)
+
+func TestLoggerWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(buf, Llevel)
+	w := logger.Writer(LevelInfo, String("source", "ffmpeg"))
+
+	fmt.Fprint(w, "first line\nsecond ")
+	fmt.Fprint(w, "line\r\n\nthird")
+	assert.Equal(t, buf.String(), strings.Join([]string{
+		`level="I" msg="first line" source="ffmpeg"`,
+		`level="I" msg="second line" source="ffmpeg"`,
+		`level="I" msg="" source="ffmpeg"`,
+	}, "\n")+"\n")
+
+	buf.Truncate(0)
+	assert.Nil(t, w.Close())
+	assert.Equal(t, buf.String(), `level="I" msg="third" source="ffmpeg"`+"\n")
+
+	_, err := w.Write([]byte("after close\n"))
+	assert.Error(t, err, ErrWriterClosed)
+	assert.Nil(t, w.Close())
+}
+
+func TestLoggerWriterLongLine(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(buf, 0)
+	w := logger.Writer(LevelInfo)
+
+	long := strings.Repeat("x", maxWriterLineLength)
+	fmt.Fprint(w, long+"yy")
+	// the first full length chunk is logged before the line is complete
+	assert.Equal(t, buf.String(), `msg="`+long+`"`+"\n")
+
+	buf.Truncate(0)
+	fmt.Fprint(w, "z\n"+long+long+"\n")
+	assert.Equal(t, buf.String(), strings.Join([]string{
+		`msg="yyz"`,
+		`msg="` + long + `"`,
+		`msg="` + long + `"`,
+	}, "\n")+"\n")
+}
+
+func TestLoggerWriterDebug(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(buf, Llevel)
+	w := logger.Writer(LevelDebug)
+	fmt.Fprintln(w, "test")
+	assert.Equal(t, buf.String(), "")
+
+	logger.SetFlags(Llevel | Ldebug)
+	fmt.Fprintln(w, "test")
+	assert.Equal(t, buf.String(), `level="D" msg="test"`+"\n")
+}
@@ -4,15 +4,180 @@
 
 package mlog
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
 
+// attrKind is the type of value held inline by an Attr.
+type attrKind uint8
+
+const (
+	kindAny attrKind = iota
+	kindString
+	kindInt64
+	kindUint64
+	kindFloat64
+	kindBool
+	kindDuration
+	kindTime
+	kindError
+)
+
+// Attr is a key value pair, used to pass extra data to the Logger functions.
+//
+// Attrs created with A (or as a struct literal) hold their value in the Value
+// field, and are formatted with fmt. Attrs created with the typed
+// constructors, such as String or Int64, hold their value inline instead of
+// boxing it in an interface{}, so they do not allocate, and leave Value nil.
+// Use Any to retrieve the value of any Attr. Emitters that are not a
+// RecordEmitter are passed copies of the Attrs, with Value set.
 type Attr struct {
 	Key   string
 	Value interface{}
+
+	kind attrKind
+	nsec int32
+	num  uint64
+	str  string
+	loc  *time.Location
 }
 
+// A returns an Attr for a value of any type.
 func A(key string, value interface{}) *Attr {
-	return &Attr{key, value}
+	return &Attr{Key: key, Value: value}
+}
+
+// String returns an Attr for a string value.
+func String(key, value string) *Attr {
+	return &Attr{Key: key, kind: kindString, str: value}
+}
+
+// Int returns an Attr for an int value, held as an int64.
+func Int(key string, value int) *Attr {
+	return Int64(key, int64(value))
+}
+
+// Int64 returns an Attr for an int64 value.
+func Int64(key string, value int64) *Attr {
+	return &Attr{Key: key, kind: kindInt64, num: uint64(value)}
+}
+
+// Uint64 returns an Attr for a uint64 value.
+func Uint64(key string, value uint64) *Attr {
+	return &Attr{Key: key, kind: kindUint64, num: value}
+}
+
+// Float64 returns an Attr for a float64 value.
+func Float64(key string, value float64) *Attr {
+	return &Attr{Key: key, kind: kindFloat64, num: math.Float64bits(value)}
+}
+
+// Bool returns an Attr for a bool value.
+func Bool(key string, value bool) *Attr {
+	var num uint64
+	if value {
+		num = 1
+	}
+	return &Attr{Key: key, kind: kindBool, num: num}
+}
+
+// Duration returns an Attr for a time.Duration value.
+func Duration(key string, value time.Duration) *Attr {
+	return &Attr{Key: key, kind: kindDuration, num: uint64(value)}
+}
+
+// Time returns an Attr for a time.Time value. The monotonic clock reading
+// of value is discarded.
+func Time(key string, value time.Time) *Attr {
+	// kept small enough to inline, so the Attr can stay on the stack
+	attr := timeAttr(key, value)
+	return &attr
+}
+
+// timeAttr returns an Attr holding t as Unix seconds, nanoseconds and
+// location, which covers the full range of time.Time. It is not inlined, to
+// keep Time small enough to inline.
+//
+//go:noinline
+func timeAttr(key string, t time.Time) Attr {
+	return Attr{
+		Key:  key,
+		kind: kindTime,
+		nsec: int32(t.Nanosecond()),
+		num:  uint64(t.Unix()),
+		loc:  t.Location(),
+	}
+}
+
+// Err returns an Attr for an error value.
+func Err(key string, err error) *Attr {
+	return &Attr{Key: key, Value: err, kind: kindError}
+}
+
+// Any returns the value of attr. For Attrs created with the typed
+// constructors, this boxes the value in an interface{}.
+func (attr *Attr) Any() interface{} {
+	switch attr.kind {
+	case kindString:
+		return attr.str
+	case kindInt64:
+		return int64(attr.num)
+	case kindUint64:
+		return attr.num
+	case kindFloat64:
+		return math.Float64frombits(attr.num)
+	case kindBool:
+		return attr.num == 1
+	case kindDuration:
+		return time.Duration(attr.num)
+	case kindTime:
+		return attr.time()
+	default:
+		return attr.Value
+	}
+}
+
+// time returns the value of an Attr created with Time.
+func (attr *Attr) time() time.Time {
+	return time.Unix(int64(attr.num), int64(attr.nsec)).In(attr.loc)
+}
+
+// StringValue returns the value of attr as an unquoted string, for outputs
+// that only hold strings. Times are formatted as RFC 3339, errors with their
+// Error method, a nil value as "", and other values with fmt.
+func (attr *Attr) StringValue() string {
+	switch attr.kind {
+	case kindString:
+		return attr.str
+	case kindInt64:
+		return strconv.FormatInt(int64(attr.num), 10)
+	case kindUint64:
+		return strconv.FormatUint(attr.num, 10)
+	case kindFloat64:
+		return strconv.FormatFloat(math.Float64frombits(attr.num), 'g', -1, 64)
+	case kindBool:
+		return strconv.FormatBool(attr.num == 1)
+	case kindDuration:
+		return time.Duration(attr.num).String()
+	case kindTime:
+		return attr.time().Format(time.RFC3339Nano)
+	}
+
+	switch v := attr.Value.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case error:
+		return v.Error()
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
 }
 
 func (attr *Attr) writeBuf(w byteSliceWriter) {
@@ -20,12 +185,62 @@ func (attr *Attr) writeBuf(w byteSliceWriter) {
 		return
 	}
 
+	w.WriteString(attr.Key)
+	w.WriteString(`="`)
+
+	switch attr.kind {
+	case kindString:
+		writeAttrString(w, attr.str)
+	case kindInt64:
+		writeInt(w, int64(attr.num))
+	case kindUint64:
+		writeUint(w, attr.num)
+	case kindFloat64:
+		writeFloat(w, math.Float64frombits(attr.num), 64)
+	case kindBool:
+		writeBool(w, attr.num == 1)
+	case kindDuration:
+		writeDuration(w, time.Duration(attr.num))
+	case kindTime:
+		writeTimeRFC3339(w, attr.time())
+	default:
+		attr.writeValueBuf(w)
+	}
+
+	w.WriteByte('"')
+}
+
+// writeValueBuf writes the fmt representation of attr.Value to w. Values of
+// basic types are written directly, which gives the same output.
+func (attr *Attr) writeValueBuf(w byteSliceWriter) {
+	switch v := attr.Value.(type) {
+	case string:
+		writeAttrString(w, v)
+		return
+	case int64:
+		writeInt(w, v)
+		return
+	case uint64:
+		writeUint(w, v)
+		return
+	case float64:
+		writeFloat(w, v, 64)
+		return
+	case bool:
+		writeBool(w, v)
+		return
+	case time.Duration:
+		writeDuration(w, v)
+		return
+	case error:
+		writeAttrString(w, v.Error())
+		return
+	}
+
 	// scratch buffer for intermediate writes
 	buf := bufPool.Get()
 	defer bufPool.Put(buf)
 
-	w.WriteString(attr.Key)
-	w.WriteString(`="`)
 	fmt.Fprint(buf, attr.Value)
 
 	// pull out byte slice from buff
@@ -55,10 +270,36 @@ func (attr *Attr) writeBuf(w byteSliceWriter) {
 	if p < blen {
 		w.Write(b[p:blen])
 	}
+}
 
-	w.WriteByte('"')
-	// truncate intermediate buf so it is clean for next loop
-	buf.Truncate(0)
+// writeAttrString writes s to w, escaped the same as the fmt representation
+// of Attr values.
+func writeAttrString(w byteSliceWriter, s string) {
+	slen := len(s)
+	p := 0
+	for i := 0; i < slen; i++ {
+		switch s[i] {
+		case '"':
+			w.WriteString(s[p:i])
+			w.WriteString(`\"`)
+			p = i + 1
+		case '\t':
+			w.WriteString(s[p:i])
+			w.WriteString(`\t`)
+			p = i + 1
+		case '\r':
+			w.WriteString(s[p:i])
+			w.WriteString(`\r`)
+			p = i + 1
+		case '\n':
+			w.WriteString(s[p:i])
+			w.WriteString(`\n`)
+			p = i + 1
+		}
+	}
+	if p < slen {
+		w.WriteString(s[p:slen])
+	}
 }
 
 func (attr *Attr) String() string {
@@ -69,8 +310,9 @@ func (attr *Attr) String() string {
 }
 
 func attrsWriteBuf(w byteSliceWriter, attrs []*Attr) {
+	attrs = filterAttrs(attrs)
 	attrsLen := len(attrs)
-	for i, attr := range filterAttrs(attrs) {
+	for i, attr := range attrs {
 		attr.writeBuf(w)
 		if i != attrsLen-1 {
 			w.WriteByte(' ')
@@ -78,6 +320,20 @@ func attrsWriteBuf(w byteSliceWriter, attrs []*Attr) {
 	}
 }
 
+// copyAttrs returns copies of the non-nil attrs, with Value set, for
+// Emitters that are not a RecordEmitter.
+func copyAttrs(attrs []*Attr) []*Attr {
+	copied := make([]*Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		if attr != nil {
+			c := *attr
+			c.Value = attr.Any()
+			copied = append(copied, &c)
+		}
+	}
+	return copied
+}
+
 func filterAttrs(attrs []*Attr) []*Attr {
 	hasNil := false
 	for _, attr := range attrs {
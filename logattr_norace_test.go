@@ -0,0 +1,42 @@
+//go:build !race
+
+// The race detector makes sync.Pool drop items, which allocates, so the
+// allocation tests are not run with it.
+
+package mlog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/dropwhile/assert"
+)
+
+func TestLogAttrTypedAllocs(t *testing.T) {
+	for _, e := range []Emitter{&FormatWriterStructured{}, &FormatWriterJSON{}, &FormatWriterPlain{}} {
+		logger := NewFormatLogger(io.Discard, Lstd, e)
+		err := errors.New("some error")
+		s := randString(10, false)
+		i := 0
+		allocs := testing.AllocsPerRun(100, func() {
+			i++
+			// up to nAttrsInline Attrs per call, as they are held in the Record
+			logger.Infox("this is a test",
+				String("string", s),
+				Int64("int64", int64(i)),
+				Uint64("uint64", uint64(i)),
+				Float64("float64", float64(i)/3),
+				Bool("bool", i%2 == 0),
+			)
+			logger.Infox("this is a test",
+				Duration("duration", time.Duration(i)*time.Millisecond),
+				Time("time", time.Now()),
+				Err("error", err),
+			)
+		})
+		assert.Equal(t, allocs, 0.0, fmt.Sprintf("unexpected allocations for %T", e))
+	}
+}
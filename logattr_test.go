@@ -1,7 +1,10 @@
 package mlog
 
 import (
+	"errors"
+	"math"
 	"testing"
+	"time"
 
 	"github.com/dropwhile/assert"
 )
@@ -25,10 +28,99 @@ func BenchmarkLogAttrWriteBuf(b *testing.B) {
 }
 
 func TestLogAttrWriteTo(t *testing.T) {
-	attr := Attr{"test", "this is \"a test\" of \t some \n a"}
+	attr := Attr{Key: "test", Value: "this is \"a test\" of \t some \n a"}
 	buf := &sliceBuffer{make([]byte, 0, 1024)}
 	attr.writeBuf(buf)
 	n := `test="this is \"a test\" of \t some \n a"`
 	l := buf.String()
 	assert.Equal(t, n, l, "did not match")
 }
+
+func TestLogAttrTyped(t *testing.T) {
+	tm := time.Date(2023, 1, 2, 3, 4, 5, 6, time.UTC)
+	var cases = []struct {
+		attr   *Attr
+		output string
+		value  interface{}
+	}{
+		{String("k", "a \"b\"\n"), `k="a \"b\"\n"`, "a \"b\"\n"},
+		{Int("k", -12), `k="-12"`, int64(-12)},
+		{Int64("k", -12), `k="-12"`, int64(-12)},
+		{Uint64("k", 12), `k="12"`, uint64(12)},
+		{Float64("k", 1.5), `k="1.5"`, 1.5},
+		{Bool("k", true), `k="true"`, true},
+		{Bool("k", false), `k="false"`, false},
+		{Duration("k", 1500*time.Millisecond), `k="1.5s"`, 1500 * time.Millisecond},
+		{Time("k", tm), `k="2023-01-02T03:04:05.000000006Z"`, tm},
+		{Time("k", time.Time{}), `k="0001-01-01T00:00:00Z"`, time.Time{}},
+		{Err("k", errors.New("some error")), `k="some error"`, errors.New("some error")},
+		{Err("k", nil), `k="<nil>"`, nil},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.output, tc.attr.String(), "unexpected output")
+		// the typed output should match the output of A, except for times,
+		// which A formats with fmt
+		if _, ok := tc.value.(time.Time); !ok {
+			assert.Equal(t, tc.output, A("k", tc.value).String(), "output differs from A")
+		}
+		assert.Equal(t, tc.value, tc.attr.Any(), "unexpected value")
+	}
+}
+
+func TestLogAttrStringValue(t *testing.T) {
+	tm := time.Date(2023, 1, 2, 3, 4, 5, 6, time.UTC)
+	var cases = []struct {
+		attr  *Attr
+		value string
+	}{
+		{String("k", "a \"b\"\n"), "a \"b\"\n"},
+		{Int("k", -12), "-12"},
+		{Float64("k", 1.5), "1.5"},
+		{Bool("k", true), "true"},
+		{Duration("k", 1500*time.Millisecond), "1.5s"},
+		{Time("k", tm), "2023-01-02T03:04:05.000000006Z"},
+		{Err("k", errors.New("some error")), "some error"},
+		{Err("k", nil), ""},
+		{A("k", []int{1, 2}), "[1 2]"},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.value, tc.attr.StringValue(), "unexpected value")
+	}
+}
+
+func TestLogAttrTime(t *testing.T) {
+	// times outside the range of UnixNano (1678 to 2262)
+	var cases = []struct {
+		value  time.Time
+		output string
+	}{
+		{time.Date(3000, 1, 2, 3, 4, 5, 6, time.UTC), `k="3000-01-02T03:04:05.000000006Z"`},
+		{time.Date(1500, 1, 2, 3, 4, 5, 6, time.UTC), `k="1500-01-02T03:04:05.000000006Z"`},
+		{time.Date(9999, 12, 31, 23, 59, 59, 0, time.FixedZone("", 3600)), `k="9999-12-31T23:59:59+01:00"`},
+	}
+
+	for _, tc := range cases {
+		attr := Time("k", tc.value)
+		assert.Equal(t, tc.output, attr.String(), "unexpected output")
+		assert.Equal(t, interface{}(tc.value), attr.Any(), "unexpected value")
+	}
+}
+
+func TestLogAttrDuration(t *testing.T) {
+	// the output should match time.Duration.String
+	for _, d := range []time.Duration{
+		0, 1, -1, 999, time.Microsecond, 1500 * time.Microsecond,
+		time.Second, -90 * time.Minute, 100*time.Hour + 1,
+		math.MaxInt64, math.MinInt64,
+	} {
+		assert.Equal(t, Duration("k", d).String(), `k="`+d.String()+`"`)
+	}
+}
+
+func TestLogAttrAnyTime(t *testing.T) {
+	// times passed to A keep their fmt output
+	tm := time.Date(2006, 1, 2, 15, 4, 5, 0, time.FixedZone("MST", -7*3600))
+	assert.Equal(t, `k="2006-01-02 15:04:05 -0700 MST"`, A("k", tm).String(), "unexpected output")
+}
@@ -5,6 +5,7 @@
 package mlog
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -13,18 +14,101 @@ import (
 )
 
 // Emitter is the interface implemented by mlog logging format writers.
+//
+// The level passed to an Emitter is -1 for debug, 0 for info and 1 for fatal.
+// Events at LevelWarn and LevelError are passed as info, so an Emitter cannot
+// tell them apart, unless it also implements LevelEmitter, which is passed
+// the Level of every event instead, or RecordEmitter, which is passed the
+// Level in the Record.
 type Emitter interface {
 	Emit(logger *Logger, level int, message string, extra Map)
 	EmitAttrs(logger *Logger, level int, message string, extra ...*Attr)
 }
 
+// LevelEmitter is an optional interface for Emitters that are passed
+// int(Level) as the level of their Emit and EmitAttrs methods, as in 4 for
+// LevelWarn, instead of -1, 0 or 1. EmitsLevel is only a marker, and is not
+// called.
+type LevelEmitter interface {
+	Emitter
+	EmitsLevel()
+}
+
 // A Logger represents a logging object, that embeds log.Logger, and
 // provides support for a toggle-able debug flag.
 type Logger struct {
+	*core
+	// attrs bound to the Logger with With
+	attrs  []*Attr
+	fields atomic.Pointer[encodedFields]
+	// set for Loggers created with Named
+	name *namedNode
+}
+
+// core holds the state shared between a Logger and any child Loggers
+// created with With.
+type core struct {
 	out   io.Writer
+	mu    sync.Mutex                // ensures atomic writes are synchronized
+	state atomic.Pointer[coreState] // may be swapped while logging
+
+	debugEsc debugEscalation
+}
+
+// coreState is the Emitter, flags and minimum Level of a core. It is
+// replaced as a whole, so that they can be changed together.
+type coreState struct {
 	e     Emitter
-	mu    sync.Mutex // ensures atomic writes are synchronized
-	flags uint64
+	flags FlagSet
+	level Level // the zero value is LevelInfo
+}
+
+// update replaces the core state with a copy of it modified by f. f may be
+// called more than once, if the state is changed concurrently.
+func (c *core) update(f func(s *coreState)) {
+	for {
+		old := c.state.Load()
+		s := *old
+		f(&s)
+		if c.state.CompareAndSwap(old, &s) {
+			return
+		}
+	}
+}
+
+// flagsHave returns true if the core flags include flag.
+func (c *core) flagsHave(flag FlagSet) bool {
+	return c.state.Load().flags&flag != 0
+}
+
+// setFlag sets or clears flag, without changing the other flags.
+func (c *core) setFlag(flag FlagSet, on bool) {
+	c.update(func(s *coreState) {
+		s.flags &^= flag
+		if on {
+			s.flags |= flag
+		}
+	})
+}
+
+// LevelFilter is an optional interface implemented by Emitters that discard
+// events at some levels. Logger.Enabled consults it, so that nothing is
+// formatted for events the Emitter would discard.
+type LevelFilter interface {
+	Enabled(logger *Logger, level Level) bool
+}
+
+// fieldEncoder is implemented by Emitters that can pre-encode the Attrs
+// bound to a Logger, so that they are not formatted again on every line.
+type fieldEncoder interface {
+	encodeFields(w byteSliceWriter, attrs []*Attr)
+}
+
+// encodedFields caches the bound Attrs of a Logger, as encoded by a
+// specific fieldEncoder.
+type encodedFields struct {
+	fe fieldEncoder
+	b  []byte
 }
 
 // SetOutput sets the Logger output io.Writer
@@ -42,168 +126,562 @@ func (l *Logger) Write(b []byte) (int, error) {
 	return l.out.Write(b)
 }
 
-// Emit invokes the FormatWriter and logs the event.
+// Flush flushes the Logger output io.Writer, if it has a Flush method, such as
+// an AsyncWriter. The Fatal and Panic methods call Flush before calling
+// os.Exit(1) or panic().
+func (l *Logger) Flush() error {
+	l.mu.Lock()
+	out := l.out
+	l.mu.Unlock()
+	return flushWriter(out)
+}
+
+// exit flushes the Logger output, then calls os.Exit(1).
+func (l *Logger) exit() {
+	_ = l.Flush()
+	os.Exit(1)
+}
+
+// panic flushes the Logger output, then calls panic(v).
+func (l *Logger) panic(v interface{}) {
+	_ = l.Flush()
+	panic(v)
+}
+
+// Emit invokes the FormatWriter and logs the event. level is as passed to an
+// Emitter: -1 for debug, 0 for info and 1 for fatal. Use EmitRecord to log
+// at other Levels.
 func (l *Logger) Emit(level int, message string, extra Map) {
-	l.e.Emit(l, level, message, extra)
+	e := l.Emitter()
+	re, ok := e.(RecordEmitter)
+	if !ok {
+		e.Emit(l, levelForEmitter(e, level), message, l.withBoundMap(extra))
+		return
+	}
+	re.EmitRecord(l, l.mapRecord(e, LevelFromEmitter(level), message, extra))
 }
 
-// Emit invokes the FormatWriter and logs the event.
+// EmitAttrs invokes the FormatWriter and logs the event. level is as passed
+// to an Emitter: -1 for debug, 0 for info and 1 for fatal. Use EmitRecord to
+// log at other Levels.
 func (l *Logger) EmitAttrs(level int, message string, extra ...*Attr) {
-	l.e.EmitAttrs(l, level, message, extra...)
+	e := l.Emitter()
+	re, ok := e.(RecordEmitter)
+	if !ok {
+		// pass copies, so that extra does not escape on the RecordEmitter
+		// path either
+		e.EmitAttrs(l, levelForEmitter(e, level), message, l.withBound(e, copyAttrs(extra))...)
+		return
+	}
+	re.EmitRecord(l, l.attrsRecord(e, LevelFromEmitter(level), message, extra))
+}
+
+// emit is Emit, for a Level.
+func (l *Logger) emit(level Level, message string, extra Map) {
+	e := l.Emitter()
+	re, ok := e.(RecordEmitter)
+	if !ok {
+		e.Emit(l, level.emitterLevelFor(e), message, l.withBoundMap(extra))
+		return
+	}
+	re.EmitRecord(l, l.mapRecord(e, level, message, extra))
+}
+
+// emitAttrs is EmitAttrs, for a Level.
+func (l *Logger) emitAttrs(level Level, message string, extra ...*Attr) {
+	e := l.Emitter()
+	re, ok := e.(RecordEmitter)
+	if !ok {
+		e.EmitAttrs(l, level.emitterLevelFor(e), message, l.withBound(e, copyAttrs(extra))...)
+		return
+	}
+	re.EmitRecord(l, l.attrsRecord(e, level, message, extra))
+}
+
+// mapRecord returns the Record for an event with extra Map elements, logged
+// through Emit or emit. The caller is resolved above the caller of those.
+func (l *Logger) mapRecord(e Emitter, level Level, message string, extra Map) Record {
+	r := l.newRecord(level, message, 3)
+	l.addBound(e, &r)
+	r.addMap(extra, l.Flags()&Lsort != 0)
+	return r
+}
+
+// attrsRecord returns the Record for an event with extra Attrs, logged
+// through EmitAttrs or emitAttrs. The caller is resolved above the caller of
+// those.
+func (l *Logger) attrsRecord(e Emitter, level Level, message string, extra []*Attr) Record {
+	r := l.newRecord(level, message, 3)
+	l.addBound(e, &r)
+	r.AddAttrs(extra...)
+	return r
+}
+
+// EmitRecord invokes the FormatWriter and logs the event described by r.
+// Emitters that are not a RecordEmitter are only passed the level, message
+// and Attrs of r, and resolve the time and caller themselves.
+func (l *Logger) EmitRecord(r Record) {
+	e := l.Emitter()
+	re, ok := e.(RecordEmitter)
+	if !ok {
+		e.EmitAttrs(l, r.Level.emitterLevelFor(e), r.Message, l.withBound(e, r.attrPtrs())...)
+		return
+	}
+
+	if l.hasUnencodedBound(e) {
+		br := NewRecord(r.Time, r.Level, r.Message, r.PC)
+		l.addBound(e, &br)
+		r.Attrs(func(attr Attr) bool {
+			br.addAttr(&attr)
+			return true
+		})
+		r = br
+	}
+	re.EmitRecord(l, r)
+}
+
+// hasUnencodedBound returns true if l has Attrs bound to it with With, and
+// the Emitter e does not encode the bound Attrs itself.
+func (l *Logger) hasUnencodedBound(e Emitter) bool {
+	if len(l.attrs) == 0 {
+		return false
+	}
+	_, ok := e.(fieldEncoder)
+	return !ok
+}
+
+// addBound adds the Attrs bound to l with With to r, unless the Emitter
+// e encodes the bound Attrs itself.
+func (l *Logger) addBound(e Emitter, r *Record) {
+	if l.hasUnencodedBound(e) {
+		r.AddAttrs(l.attrs...)
+	}
+}
+
+// withBound prepends the Attrs bound to l with With to extra, unless the
+// Emitter e encodes the bound Attrs itself.
+func (l *Logger) withBound(e Emitter, extra []*Attr) []*Attr {
+	if l.hasUnencodedBound(e) {
+		return append(l.attrs[:len(l.attrs):len(l.attrs)], extra...)
+	}
+	return extra
+}
+
+// withBoundMap merges the Attrs bound to l with With and m into a new Map,
+// for Emitters that are not a RecordEmitter.
+func (l *Logger) withBoundMap(m Map) Map {
+	if len(l.attrs) == 0 {
+		return m
+	}
+	bound := make(Map, len(l.attrs)+len(m))
+	for _, attr := range l.attrs {
+		bound[attr.Key] = attr.Any()
+	}
+	for k, v := range m {
+		bound[k] = v
+	}
+	return bound
+}
+
+// With returns a new child Logger, that shares the output, flags and Emitter
+// of l, and that includes attrs in every log line it emits.
+func (l *Logger) With(attrs ...*Attr) *Logger {
+	attrs = filterAttrs(attrs)
+	if len(attrs) == 0 {
+		return l
+	}
+	bound := make([]*Attr, 0, len(l.attrs)+len(attrs))
+	bound = append(bound, l.attrs...)
+	bound = append(bound, attrs...)
+	return &Logger{core: l.core, attrs: bound, name: l.name}
 }
 
-// SetEmitter sets the Emitter
+// boundFields returns the Attrs bound to l with With, as pre-encoded by fe.
+// The encoding is done once, and then cached for subsequent calls.
+func (l *Logger) boundFields(fe fieldEncoder) []byte {
+	if len(l.attrs) == 0 {
+		return nil
+	}
+	if ef := l.fields.Load(); ef != nil && ef.fe == fe {
+		return ef.b
+	}
+	sb := &sliceBuffer{make([]byte, 0, 64*len(l.attrs))}
+	fe.encodeFields(sb, l.attrs)
+	l.fields.Store(&encodedFields{fe: fe, b: sb.Bytes()})
+	return sb.Bytes()
+}
+
+// Emitter returns the current Emitter
+func (l *Logger) Emitter() Emitter {
+	return l.state.Load().e
+}
+
+// SetEmitter sets the Emitter. It is safe to call while logging, and affects
+// all Loggers sharing the output of l (see Logger.With).
 func (l *Logger) SetEmitter(e Emitter) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.e = e
+	l.update(func(s *coreState) { s.e = e })
 }
 
-// Flags returns the current FlagSet
+// Flags returns the current FlagSet. For a named Logger, this is the FlagSet
+// set with SetNamedFlags, if any.
 func (l *Logger) Flags() FlagSet {
-	return FlagSet(atomic.LoadUint64(&l.flags))
+	if l.name != nil {
+		if s := l.name.load(); s.hasFlags {
+			return s.flags
+		}
+	}
+	return l.state.Load().flags
 }
 
 // SetFlags sets the current FlagSet
 func (l *Logger) SetFlags(flags FlagSet) {
-	atomic.StoreUint64(&l.flags, uint64(flags))
+	l.update(func(s *coreState) { s.flags = flags })
+}
+
+// UpdateFlags atomically replaces the FlagSet returned by Flags with f
+// applied to it. For a named Logger with a FlagSet set with SetNamedFlags,
+// the result is set for its name, as with SetNamedFlags; otherwise it is set
+// as with SetFlags.
+func (l *Logger) UpdateFlags(f func(FlagSet) FlagSet) {
+	if l.name != nil && namedLoggers.updateFlags(l.name.name, f) {
+		return
+	}
+	l.update(func(s *coreState) { s.flags = f(s.flags) })
 }
 
 // HasDebug returns true if the debug logging FlagSet is enabled, false
 // otherwise.
 func (l *Logger) HasDebug() bool {
-	flags := FlagSet(atomic.LoadUint64(&l.flags))
-	return flags&Ldebug != 0
+	return l.Flags()&Ldebug != 0
+}
+
+// Level returns the minimum Level of the Logger. For a named Logger, this is
+// the Level set with SetNamedLevel, if any.
+func (l *Logger) Level() Level {
+	if l.name != nil {
+		if s := l.name.load(); s.hasLevel {
+			return s.level
+		}
+	}
+	return l.state.Load().level
+}
+
+// SetLevel sets the minimum Level of the Logger. Events below the minimum
+// Level are not logged. The default minimum Level is LevelInfo.
+func (l *Logger) SetLevel(level Level) {
+	l.update(func(s *coreState) { s.level = level })
+}
+
+// UpdateLevel atomically replaces the Level returned by Level with f applied
+// to it. For a named Logger with a Level set with SetNamedLevel, the result
+// is set for its name, as with SetNamedLevel; otherwise it is set as with
+// SetLevel.
+func (l *Logger) UpdateLevel(f func(Level) Level) {
+	if l.name != nil && namedLoggers.updateLevel(l.name.name, f) {
+		return
+	}
+	l.update(func(s *coreState) { s.level = f(s.level) })
+}
+
+// Enabled returns true if events at level are logged by the Logger.
+//
+// An event is logged if level is at or above the minimum Level, and the
+// Emitter (if it implements LevelFilter) does not discard it. The Ldebug flag
+// lowers the minimum Level to LevelDebug.
+func (l *Logger) Enabled(level Level) bool {
+	minLevel := l.Level()
+	if minLevel > LevelDebug && l.Flags()&Ldebug != 0 {
+		minLevel = LevelDebug
+	}
+	if level < minLevel {
+		return false
+	}
+	if lf, ok := l.Emitter().(LevelFilter); ok {
+		return lf.Enabled(l, level)
+	}
+	return true
 }
 
 // Debugx conditionally logs message and any Attr elements at level="debug".
-// If the Logger does not have the Ldebug flag, nothing is logged.
+// If LevelDebug is not enabled for the Logger or the call site, nothing is
+// logged. See SetDebugPattern.
 func (l *Logger) Debugx(message string, attrs ...*Attr) {
-	if l.HasDebug() {
-		l.EmitAttrs(-1, message, attrs...)
+	if l.debugEnabled() {
+		l.emitAttrs(LevelDebug, message, attrs...)
 	}
 }
 
 // Infox logs message and any Map elements at level="info".
 func (l *Logger) Infox(message string, attrs ...*Attr) {
-	l.EmitAttrs(0, message, attrs...)
+	if l.Enabled(LevelInfo) {
+		l.emitAttrs(LevelInfo, message, attrs...)
+	}
 }
 
 // Printx logs message and any Map elements at level="info".
 func (l *Logger) Printx(message string, attrs ...*Attr) {
-	l.EmitAttrs(0, message, attrs...)
+	if l.Enabled(LevelInfo) {
+		l.emitAttrs(LevelInfo, message, attrs...)
+	}
+}
+
+// Warnx logs message and any Attr elements at level="warn".
+func (l *Logger) Warnx(message string, attrs ...*Attr) {
+	if l.Enabled(LevelWarn) {
+		l.emitAttrs(LevelWarn, message, attrs...)
+	}
+}
+
+// Errorx logs message and any Attr elements at level="error".
+func (l *Logger) Errorx(message string, attrs ...*Attr) {
+	if l.Enabled(LevelError) {
+		l.emitAttrs(LevelError, message, attrs...)
+	}
 }
 
 // Fatalx logs message and any Map elements at level="fatal", then calls
 // os.Exit(1)
 func (l *Logger) Fatalx(message string, attrs ...*Attr) {
-	l.EmitAttrs(1, message, attrs...)
-	os.Exit(1)
+	if l.Enabled(LevelFatal) {
+		l.emitAttrs(LevelFatal, message, attrs...)
+	}
+	l.exit()
 }
 
 // Panicx logs message and any Map elements at level="fatal", then calls
 // panic().
 func (l *Logger) Panicx(message string, attrs ...*Attr) {
-	l.EmitAttrs(1, message, attrs...)
-	panic(message)
+	if l.Enabled(LevelFatal) {
+		l.emitAttrs(LevelFatal, message, attrs...)
+	}
+	l.panic(message)
+}
+
+// DebugxCtx conditionally logs message and any Attr elements at
+// level="debug", along with any Attrs extracted from ctx.
+// If LevelDebug is not enabled for the Logger or the call site, nothing is
+// logged. See SetDebugPattern.
+func (l *Logger) DebugxCtx(ctx context.Context, message string, attrs ...*Attr) {
+	if l.debugEnabled() {
+		l.emitAttrs(LevelDebug, message, contextAttrs(ctx, attrs)...)
+	}
+}
+
+// InfoxCtx logs message and any Attr elements at level="info", along with
+// any Attrs extracted from ctx.
+func (l *Logger) InfoxCtx(ctx context.Context, message string, attrs ...*Attr) {
+	if l.Enabled(LevelInfo) {
+		l.emitAttrs(LevelInfo, message, contextAttrs(ctx, attrs)...)
+	}
+}
+
+// PrintxCtx logs message and any Attr elements at level="info", along with
+// any Attrs extracted from ctx.
+func (l *Logger) PrintxCtx(ctx context.Context, message string, attrs ...*Attr) {
+	if l.Enabled(LevelInfo) {
+		l.emitAttrs(LevelInfo, message, contextAttrs(ctx, attrs)...)
+	}
+}
+
+// WarnxCtx logs message and any Attr elements at level="warn", along with
+// any Attrs extracted from ctx.
+func (l *Logger) WarnxCtx(ctx context.Context, message string, attrs ...*Attr) {
+	if l.Enabled(LevelWarn) {
+		l.emitAttrs(LevelWarn, message, contextAttrs(ctx, attrs)...)
+	}
+}
+
+// ErrorxCtx logs message and any Attr elements at level="error", along with
+// any Attrs extracted from ctx.
+func (l *Logger) ErrorxCtx(ctx context.Context, message string, attrs ...*Attr) {
+	if l.Enabled(LevelError) {
+		l.emitAttrs(LevelError, message, contextAttrs(ctx, attrs)...)
+	}
+}
+
+// FatalxCtx logs message and any Attr elements at level="fatal", along with
+// any Attrs extracted from ctx, then calls os.Exit(1)
+func (l *Logger) FatalxCtx(ctx context.Context, message string, attrs ...*Attr) {
+	if l.Enabled(LevelFatal) {
+		l.emitAttrs(LevelFatal, message, contextAttrs(ctx, attrs)...)
+	}
+	l.exit()
+}
+
+// PanicxCtx logs message and any Attr elements at level="fatal", along with
+// any Attrs extracted from ctx, then calls panic().
+func (l *Logger) PanicxCtx(ctx context.Context, message string, attrs ...*Attr) {
+	if l.Enabled(LevelFatal) {
+		l.emitAttrs(LevelFatal, message, contextAttrs(ctx, attrs)...)
+	}
+	l.panic(message)
 }
 
 // Debugm conditionally logs message and any Map elements at level="debug".
-// If the Logger does not have the Ldebug flag, nothing is logged.
+// If LevelDebug is not enabled for the Logger or the call site, nothing is
+// logged. See SetDebugPattern.
 func (l *Logger) Debugm(message string, v Map) {
-	if l.HasDebug() {
-		l.Emit(-1, message, v)
+	if l.debugEnabled() {
+		l.emit(LevelDebug, message, v)
 	}
 }
 
 // Infom logs message and any Map elements at level="info".
 func (l *Logger) Infom(message string, v Map) {
-	l.Emit(0, message, v)
+	if l.Enabled(LevelInfo) {
+		l.emit(LevelInfo, message, v)
+	}
 }
 
 // Printm logs message and any Map elements at level="info".
 func (l *Logger) Printm(message string, v Map) {
-	l.Emit(0, message, v)
+	if l.Enabled(LevelInfo) {
+		l.emit(LevelInfo, message, v)
+	}
+}
+
+// Warnm logs message and any Map elements at level="warn".
+func (l *Logger) Warnm(message string, v Map) {
+	if l.Enabled(LevelWarn) {
+		l.emit(LevelWarn, message, v)
+	}
+}
+
+// Errorm logs message and any Map elements at level="error".
+func (l *Logger) Errorm(message string, v Map) {
+	if l.Enabled(LevelError) {
+		l.emit(LevelError, message, v)
+	}
 }
 
 // Fatalm logs message and any Map elements at level="fatal", then calls
 // os.Exit(1)
 func (l *Logger) Fatalm(message string, v Map) {
-	l.Emit(1, message, v)
-	os.Exit(1)
+	if l.Enabled(LevelFatal) {
+		l.emit(LevelFatal, message, v)
+	}
+	l.exit()
 }
 
 // Panicm logs message and any Map elements at level="fatal", then calls
 // panic().
 func (l *Logger) Panicm(message string, v Map) {
-	l.Emit(1, message, v)
-	panic(message)
+	if l.Enabled(LevelFatal) {
+		l.emit(LevelFatal, message, v)
+	}
+	l.panic(message)
 }
 
 // Debugf formats and conditionally logs message at level="debug".
-// If the Logger does not have the Ldebug flag, nothing is logged.
+// If LevelDebug is not enabled for the Logger or the call site, nothing is
+// logged. See SetDebugPattern.
 func (l *Logger) Debugf(format string, v ...interface{}) {
-	if l.HasDebug() {
-		l.Emit(-1, fmt.Sprintf(format, v...), nil)
+	if l.debugEnabled() {
+		l.emit(LevelDebug, fmt.Sprintf(format, v...), nil)
 	}
 }
 
 // Infof formats and logs message at level="info".
 func (l *Logger) Infof(format string, v ...interface{}) {
-	l.Emit(0, fmt.Sprintf(format, v...), nil)
+	if l.Enabled(LevelInfo) {
+		l.emit(LevelInfo, fmt.Sprintf(format, v...), nil)
+	}
 }
 
 // Printf formats and logs message at level="info".
 func (l *Logger) Printf(format string, v ...interface{}) {
-	l.Emit(0, fmt.Sprintf(format, v...), nil)
+	if l.Enabled(LevelInfo) {
+		l.emit(LevelInfo, fmt.Sprintf(format, v...), nil)
+	}
+}
+
+// Warnf formats and logs message at level="warn".
+func (l *Logger) Warnf(format string, v ...interface{}) {
+	if l.Enabled(LevelWarn) {
+		l.emit(LevelWarn, fmt.Sprintf(format, v...), nil)
+	}
+}
+
+// Errorf formats and logs message at level="error".
+func (l *Logger) Errorf(format string, v ...interface{}) {
+	if l.Enabled(LevelError) {
+		l.emit(LevelError, fmt.Sprintf(format, v...), nil)
+	}
 }
 
 // Fatalf formats and logs message at level="fatal", then calls
 // os.Exit(1)
 func (l *Logger) Fatalf(format string, v ...interface{}) {
-	l.Emit(1, fmt.Sprintf(format, v...), nil)
-	os.Exit(1)
+	if l.Enabled(LevelFatal) {
+		l.emit(LevelFatal, fmt.Sprintf(format, v...), nil)
+	}
+	l.exit()
 }
 
 // Panicf formats and logs message at level="fatal", then calls
 // panic().
 func (l *Logger) Panicf(format string, v ...interface{}) {
 	s := fmt.Sprintf(format, v...)
-	l.Emit(1, s, nil)
-	panic(s)
+	if l.Enabled(LevelFatal) {
+		l.emit(LevelFatal, s, nil)
+	}
+	l.panic(s)
 }
 
 // Debug conditionally logs message at level="debug".
-// If the Logger does not have the Ldebug flag, nothing is logged.
+// If LevelDebug is not enabled for the Logger or the call site, nothing is
+// logged. See SetDebugPattern.
 func (l *Logger) Debug(v ...interface{}) {
-	if l.HasDebug() {
-		l.Emit(-1, fmt.Sprint(v...), nil)
+	if l.debugEnabled() {
+		l.emit(LevelDebug, fmt.Sprint(v...), nil)
 	}
 }
 
 // Info logs message at level="info".
 func (l *Logger) Info(v ...interface{}) {
-	l.Emit(0, fmt.Sprint(v...), nil)
+	if l.Enabled(LevelInfo) {
+		l.emit(LevelInfo, fmt.Sprint(v...), nil)
+	}
 }
 
 // Print logs message at level="info".
 func (l *Logger) Print(v ...interface{}) {
-	l.Emit(0, fmt.Sprint(v...), nil)
+	if l.Enabled(LevelInfo) {
+		l.emit(LevelInfo, fmt.Sprint(v...), nil)
+	}
+}
+
+// Warn logs message at level="warn".
+func (l *Logger) Warn(v ...interface{}) {
+	if l.Enabled(LevelWarn) {
+		l.emit(LevelWarn, fmt.Sprint(v...), nil)
+	}
+}
+
+// Error logs message at level="error".
+func (l *Logger) Error(v ...interface{}) {
+	if l.Enabled(LevelError) {
+		l.emit(LevelError, fmt.Sprint(v...), nil)
+	}
 }
 
 // Fatal logs message at level="fatal", then calls
 // os.Exit(1)
 func (l *Logger) Fatal(v ...interface{}) {
-	l.Emit(1, fmt.Sprint(v...), nil)
-	os.Exit(1)
+	if l.Enabled(LevelFatal) {
+		l.emit(LevelFatal, fmt.Sprint(v...), nil)
+	}
+	l.exit()
 }
 
 // Panic logs message at level="fatal", then calls
 // panic().
 func (l *Logger) Panic(v ...interface{}) {
 	s := fmt.Sprint(v...)
-	l.Emit(1, s, nil)
-	panic(s)
+	if l.Enabled(LevelFatal) {
+		l.emit(LevelFatal, s, nil)
+	}
+	l.panic(s)
 }
 
 // New creates a new Logger.
@@ -213,9 +691,7 @@ func New(out io.Writer, flags FlagSet) *Logger {
 
 // NewFormatLogger creates a new Logger, using the specified Emitter.
 func NewFormatLogger(out io.Writer, flags FlagSet, e Emitter) *Logger {
-	return &Logger{
-		out:   out,
-		flags: uint64(flags),
-		e:     e,
-	}
+	l := &Logger{core: &core{out: out}}
+	l.state.Store(&coreState{e: e, flags: flags})
+	return l
 }
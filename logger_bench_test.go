@@ -5,10 +5,12 @@
 package mlog
 
 import (
+	"errors"
 	"io"
 	"log"
 	"math/rand"
 	"testing"
+	"time"
 )
 
 const (
@@ -108,3 +110,81 @@ func BenchmarkLoggingParallelStdlibLog(b *testing.B) {
 		}
 	})
 }
+
+func BenchmarkLoggingInfoxTypedAttrs(b *testing.B) {
+	logger := New(io.Discard, Lstd)
+	err := errors.New("an error")
+	s := randString(10, false)
+	benchmarkZeroAllocs(b, func(i int) {
+		logger.Infox("this is a test",
+			String("string", s),
+			Int64("int64", int64(i)),
+			Float64("float64", float64(i)/3),
+			Duration("duration", time.Duration(i)*time.Millisecond),
+			Err("error", err),
+		)
+	})
+}
+
+func BenchmarkLoggingInfoxTypedAttrsJSON(b *testing.B) {
+	logger := NewFormatLogger(io.Discard, Lstd, &FormatWriterJSON{})
+	s := randString(10, false)
+	benchmarkZeroAllocs(b, func(i int) {
+		logger.Infox("this is a test",
+			String("string", s),
+			Int64("int64", int64(i)),
+			Uint64("uint64", uint64(i)),
+			Bool("bool", i%2 == 0),
+			Time("time", time.Now()),
+		)
+	})
+}
+
+// benchmarkZeroAllocs fails b if f allocates, then runs f b.N times.
+func benchmarkZeroAllocs(b *testing.B, f func(i int)) {
+	i := 0
+	if allocs := testing.AllocsPerRun(100, func() { i++; f(i) }); allocs != 0 {
+		b.Fatalf("unexpected allocations: %v", allocs)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f(i)
+	}
+}
+
+func BenchmarkLoggingInfoxAnyAttrs(b *testing.B) {
+	logger := New(io.Discard, Lstd)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Infox("this is a test",
+			A("string", "value"),
+			A("int64", int64(i)),
+			A("float64", 1.5),
+		)
+	}
+}
+
+func BenchmarkLoggerEnabled(b *testing.B) {
+	logger := New(io.Discard, Lstd)
+	logger.SetLevel(LevelWarn)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Infof("this is a test: %d", i)
+	}
+}
+
+func BenchmarkLoggerDebugPatternDisabled(b *testing.B) {
+	logger := New(io.Discard, Lstd)
+	if err := SetDebugPattern("other.go=on"); err != nil {
+		b.Fatal(err)
+	}
+	defer SetDebugPattern("")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Debugx("this is a test")
+	}
+}
@@ -40,12 +40,18 @@ func TestLoggerMsgs(t *testing.T) {
 		"infof1":  {Llevel, "infof", "test: %d", 5},
 		"infof2":  {Llevel, "infof", "test: %s", "test"},
 		"infof3":  {Llevel, "infof", "test: %s %s", []interface{}{"test", "pickles"}},
-		"infox1":  {Llevel, "infox", "test", []*Attr{{"x", "y"}}},
-		"infox2":  {Llevel, "infox", "test", []*Attr{{"x", "y"}, {"y", "z"}}},
+		"infox1":  {Llevel, "infox", "test", []*Attr{{Key: "x", Value: "y"}}},
+		"infox2":  {Llevel, "infox", "test", []*Attr{{Key: "x", Value: "y"}, {Key: "y", Value: "z"}}},
 		"infox3":  {Llevel, "infox", "test", nil},
-		"debugx1": {Llevel | Ldebug, "debugx", "test", []*Attr{{"x", "y"}}},
-		"debugx2": {Llevel | Ldebug, "debugx", "test", []*Attr{{"x", "y"}, {"y", "z"}}},
+		"debugx1": {Llevel | Ldebug, "debugx", "test", []*Attr{{Key: "x", Value: "y"}}},
+		"debugx2": {Llevel | Ldebug, "debugx", "test", []*Attr{{Key: "x", Value: "y"}, {Key: "y", Value: "z"}}},
 		"debugx3": {Llevel | Ldebug, "debugx", "test", nil},
+		"warnx1":  {Llevel, "warnx", "test", []*Attr{{Key: "x", Value: "y"}}},
+		"warnm1":  {Llevel | Lsort, "warnm", "test", Map{"x": "y"}},
+		"warnf1":  {Llevel, "warnf", "test: %d", 5},
+		"errorx1": {Llevel, "errorx", "test", []*Attr{{Key: "x", Value: "y"}}},
+		"errorm1": {Llevel | Lsort, "errorm", "test", Map{"x": "y"}},
+		"errorf1": {Llevel, "errorf", "test: %d", 5},
 	}
 
 	buf := &bytes.Buffer{}
@@ -54,7 +60,7 @@ func TestLoggerMsgs(t *testing.T) {
 
 	for name, tt := range infoTests {
 		buf.Truncate(0)
-		logger.flags = uint64(tt.flags)
+		logger.SetFlags(tt.flags)
 
 		switch tt.method {
 		case "debugx":
@@ -85,6 +91,18 @@ func TestLoggerMsgs(t *testing.T) {
 				continue
 			}
 			logger.Infom(tt.message, m)
+		case "warnx":
+			logger.Warnx(tt.message, tt.extra.([]*Attr)...)
+		case "errorx":
+			logger.Errorx(tt.message, tt.extra.([]*Attr)...)
+		case "warnm":
+			logger.Warnm(tt.message, tt.extra.(Map))
+		case "errorm":
+			logger.Errorm(tt.message, tt.extra.(Map))
+		case "warnf":
+			logger.Warnf(tt.message, tt.extra)
+		case "errorf":
+			logger.Errorf(tt.message, tt.extra)
 		case "debug":
 			logger.Debug(tt.message)
 		case "info":
@@ -164,7 +182,7 @@ func TestPanics(t *testing.T) {
 
 	for name, tt := range infoTests {
 		buf.Truncate(0)
-		logger.flags = uint64(tt.flags)
+		logger.SetFlags(tt.flags)
 
 		switch tt.method {
 		case "panicm":
@@ -193,3 +211,89 @@ func TestPanics(t *testing.T) {
 		golden.AssertBytes(t, buf.Bytes(), goldenFixture, "%s: did not match expectation", name)
 	}
 }
+
+func TestLoggerWith(t *testing.T) {
+	withTests := map[string]struct {
+		emitter Emitter
+		method  string
+		extra   interface{}
+		output  string
+	}{
+		"structured-x":  {&FormatWriterStructured{}, "infox", []*Attr{{Key: "z", Value: "1"}}, `level="I" msg="test" x="y" y="z" z="1"`},
+		"structured-m":  {&FormatWriterStructured{}, "infom", Map{"z": "1"}, `level="I" msg="test" x="y" y="z" z="1"`},
+		"structured-x0": {&FormatWriterStructured{}, "infox", nil, `level="I" msg="test" x="y" y="z"`},
+		"json-x":        {&FormatWriterJSON{}, "infox", []*Attr{{Key: "z", Value: "1"}}, `{"level": "I", "msg": "test", "extra": {"x": "y", "y": "z", "z": "1"}}`},
+		"json-m":        {&FormatWriterJSON{}, "infom", Map{"z": "1"}, `{"level": "I", "msg": "test", "extra": {"x": "y", "y": "z", "z": "1"}}`},
+		"json-x0":       {&FormatWriterJSON{}, "infox", nil, `{"level": "I", "msg": "test", "extra": {"x": "y", "y": "z"}}`},
+		"plain-x":       {&FormatWriterPlain{}, "infox", []*Attr{{Key: "z", Value: "1"}}, `INFO  test x="y" y="z" z="1"`},
+		"plain-m":       {&FormatWriterPlain{}, "infom", Map{"z": "1"}, `INFO  test x="y" y="z" z="1"`},
+		"plain-x0":      {&FormatWriterPlain{}, "infox", nil, `INFO  test x="y" y="z"`},
+	}
+
+	buf := &bytes.Buffer{}
+	for name, tt := range withTests {
+		buf.Truncate(0)
+		logger := NewFormatLogger(buf, Llevel|Lsort, tt.emitter)
+		child := logger.With(A("x", "y")).With(A("y", "z"), nil)
+
+		switch tt.method {
+		case "infox":
+			m, _ := tt.extra.([]*Attr)
+			child.Infox("test", m...)
+		case "infom":
+			m, _ := tt.extra.(Map)
+			child.Infom("test", m)
+		}
+		assert.Equal(t, buf.String(), tt.output+"\n", fmt.Sprintf("%s: did not match expectation", name))
+
+		// parent logger should be unaffected
+		buf.Truncate(0)
+		logger.Info("test")
+		assert.False(t, bytes.Contains(buf.Bytes(), []byte("x=")) || bytes.Contains(buf.Bytes(), []byte("extra")),
+			fmt.Sprintf("%s: parent logger had bound attrs", name))
+	}
+}
+
+func TestLoggerWithSharesCore(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(io.Discard, 0)
+	child := logger.With(A("x", "y"))
+
+	logger.SetOutput(buf)
+	logger.SetFlags(Llevel | Ldebug)
+	child.Debug("test")
+	assert.Equal(t, buf.String(), "level=\"D\" msg=\"test\" x=\"y\"\n")
+
+	// custom emitters get the bound attrs as extra
+	buf.Truncate(0)
+	child.SetEmitter(&testEmitter{})
+	child.Infox("test", A("z", "1"))
+	assert.Equal(t, buf.String(), "test x=y z=1\n")
+}
+
+type testEmitter struct{}
+
+func (e *testEmitter) Emit(logger *Logger, level int, message string, extra Map) {
+	fmt.Fprintln(logger, message, extra.SortedString())
+}
+
+func (e *testEmitter) EmitAttrs(logger *Logger, level int, message string, extra ...*Attr) {
+	s := message
+	for _, attr := range extra {
+		s += fmt.Sprintf(" %s=%v", attr.Key, attr.Value)
+	}
+	fmt.Fprintln(logger, s)
+}
+
+func TestLoggerCaller(t *testing.T) {
+	buf := &bytes.Buffer{}
+	for _, e := range []Emitter{&FormatWriterStructured{}, &FormatWriterJSON{}, &FormatWriterPlain{}} {
+		buf.Truncate(0)
+		logger := NewFormatLogger(buf, Lshortfile, e)
+		logger.Infox("test")
+		logger.Infom("test", nil)
+		for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte{'\n'}) {
+			assert.MatchesRegex(t, string(line), `logger_test.go:[0-9]+`)
+		}
+	}
+}
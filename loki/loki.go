@@ -0,0 +1,478 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Package loki provides an mlog Emitter that sends log events to Grafana
+// Loki, using its /loki/api/v1/push json API.
+//
+// Example usage:
+//
+//	e, err := loki.New(loki.Options{
+//	    URL:       "http://loki:3100",
+//	    Labels:    map[string]string{"env": "prod"},
+//	    LabelKeys: []string{"service", "level"},
+//	})
+//	if err != nil {
+//	    // handle error
+//	}
+//	defer e.Close()
+//	logger := mlog.NewFormatLogger(io.Discard, mlog.Lshortfile, e)
+//
+// Attrs with a key in LabelKeys are promoted to stream labels, and removed
+// from the log line. The "level" key promotes the level of each event, as in
+// level="warn". The log line holds the level, message, caller and remaining
+// Attrs, in the FormatWriterStructured format, or as json. Attrs named level,
+// msg or caller are prefixed with "attr_", so they do not replace those. The
+// Logger output is not used.
+//
+// Events are sent in batches, by size and time, from a background goroutine.
+// Failed pushes are retried with exponential backoff.
+package loki
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cactus/mlog"
+)
+
+// PushPath is the path of the Loki push API.
+const PushPath = "/loki/api/v1/push"
+
+// Defaults for Options.
+const (
+	DefaultBatchSize  = 1 << 20
+	DefaultBatchWait  = time.Second
+	DefaultMaxRetries = 5
+	DefaultMinBackoff = 500 * time.Millisecond
+	DefaultMaxBackoff = 30 * time.Second
+	DefaultTimeout    = 10 * time.Second
+)
+
+// reservedKeys are the keys of the log line set by the Emitter, which Attrs
+// are not sent as.
+var reservedKeys = map[string]bool{
+	"level":  true,
+	"msg":    true,
+	"caller": true,
+}
+
+// maxPendingBatches is the number of full batches that are held while a push
+// is failing, after which new events are dropped.
+const maxPendingBatches = 4
+
+// Options configures an Emitter.
+type Options struct {
+	// URL is the base URL of the Loki server, such as "http://loki:3100".
+	URL string
+	// TenantID is sent as the X-Scope-OrgID header, if set.
+	TenantID string
+	// Labels are static labels added to every stream. Loki rejects streams
+	// without labels, so if Labels is empty, a "job" label with the program
+	// name is added.
+	Labels map[string]string
+	// LabelKeys are the Attr keys promoted to stream labels. The "level" key
+	// promotes the event level.
+	LabelKeys []string
+	// JSON formats the log line as json, instead of in the
+	// FormatWriterStructured format.
+	JSON bool
+	// BatchSize is the size in bytes of log lines at which a batch is sent.
+	// Defaults to DefaultBatchSize.
+	BatchSize int
+	// BatchWait is the maximum time an event is held before it is sent.
+	// Defaults to DefaultBatchWait.
+	BatchWait time.Duration
+	// MaxRetries is the number of times a failed push is retried. Defaults to
+	// DefaultMaxRetries. Pushes rejected with a 4xx status (other than 429)
+	// are not retried.
+	MaxRetries int
+	// MinBackoff and MaxBackoff bound the exponential backoff between
+	// retries. Default to DefaultMinBackoff and DefaultMaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Gzip compresses the push requests.
+	Gzip bool
+	// Client is the http.Client used to push. Defaults to an http.Client
+	// with a DefaultTimeout timeout.
+	Client *http.Client
+}
+
+// Emitter is an mlog Emitter that sends log events to Loki. It is safe for
+// concurrent use.
+type Emitter struct {
+	opts      Options
+	pushURL   string
+	labelKeys map[string]bool
+	labels    map[string]string
+
+	mu    sync.Mutex
+	batch *batch
+	// closed is set by Close
+	closed bool
+
+	// pushMu serializes pushes
+	pushMu sync.Mutex
+	full   chan struct{}
+	done   chan struct{}
+	wg     sync.WaitGroup
+	// ctx is the context of push requests, canceled by Close
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// batch holds the events to send, grouped into streams by label set.
+type batch struct {
+	streams map[string]*stream
+	size    int
+}
+
+// stream is the json representation of a Loki stream.
+type stream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// pushRequest is the json body of a push request.
+type pushRequest struct {
+	Streams []*stream `json:"streams"`
+}
+
+// New returns an Emitter pushing to the Loki server described by opts.
+func New(opts Options) (*Emitter, error) {
+	if opts.URL == "" {
+		return nil, errors.New("loki: URL is required")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.BatchWait <= 0 {
+		opts.BatchWait = DefaultBatchWait
+	}
+	if opts.MaxRetries <= 0 {
+		opts.MaxRetries = DefaultMaxRetries
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = DefaultMinBackoff
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = DefaultMaxBackoff
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: DefaultTimeout}
+	}
+
+	e := &Emitter{
+		opts:      opts,
+		pushURL:   strings.TrimSuffix(opts.URL, "/") + PushPath,
+		labelKeys: make(map[string]bool, len(opts.LabelKeys)),
+		labels:    make(map[string]string, len(opts.Labels)),
+		batch:     newBatch(),
+		full:      make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+	e.ctx, e.cancel = context.WithCancel(context.Background())
+	for _, k := range opts.LabelKeys {
+		e.labelKeys[k] = true
+	}
+	for k, v := range opts.Labels {
+		e.labels[labelName(k)] = v
+	}
+	if len(e.labels) == 0 {
+		e.labels["job"] = filepath.Base(os.Args[0])
+	}
+
+	e.wg.Add(1)
+	go e.run()
+	return e, nil
+}
+
+func newBatch() *batch {
+	return &batch{streams: make(map[string]*stream)}
+}
+
+// run pushes batches when they are full, or every BatchWait.
+func (e *Emitter) run() {
+	defer e.wg.Done()
+	ticker := time.NewTicker(e.opts.BatchWait)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-e.full:
+		case <-e.done:
+			return
+		}
+		_ = e.Flush()
+	}
+}
+
+// Flush pushes any batched events.
+func (e *Emitter) Flush() error {
+	e.pushMu.Lock()
+	defer e.pushMu.Unlock()
+
+	e.mu.Lock()
+	b := e.batch
+	e.batch = newBatch()
+	e.mu.Unlock()
+
+	if b.size == 0 {
+		return nil
+	}
+	return e.push(b)
+}
+
+// Close stops the background goroutine, and pushes any batched events.
+// Failed pushes are no longer retried once Close is called, so Close waits at
+// most for the push in progress, if any, and one last push, each bounded by
+// the Client timeout.
+func (e *Emitter) Close() error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
+	}
+	e.closed = true
+	e.mu.Unlock()
+
+	close(e.done)
+	e.wg.Wait()
+	err := e.Flush()
+	e.cancel()
+	return err
+}
+
+// Emit sends a log event (with nillable extra Map). A Logger calls EmitRecord
+// instead.
+func (e *Emitter) Emit(logger *mlog.Logger, level int, message string, extra mlog.Map) {
+	mlog.EmitMapRecord(e, logger, level, message, extra)
+}
+
+// EmitAttrs sends a log event (with optional extra Attrs). A Logger calls
+// EmitRecord instead.
+func (e *Emitter) EmitAttrs(logger *mlog.Logger, level int, message string, extra ...*mlog.Attr) {
+	mlog.EmitAttrsRecord(e, logger, level, message, extra)
+}
+
+// EmitRecord adds r to the current batch.
+func (e *Emitter) EmitRecord(logger *mlog.Logger, r mlog.Record) {
+	labels, line := e.encode(logger, r)
+	key := labelsKey(labels)
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed || e.batch.size >= maxPendingBatches*e.opts.BatchSize {
+		return
+	}
+
+	s, ok := e.batch.streams[key]
+	if !ok {
+		s = &stream{Stream: labels}
+		e.batch.streams[key] = s
+	}
+	s.Values = append(s.Values, [2]string{strconv.FormatInt(t.UnixNano(), 10), line})
+	e.batch.size += len(line)
+
+	if e.batch.size >= e.opts.BatchSize {
+		select {
+		case e.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// encode returns the stream labels and log line for r.
+func (e *Emitter) encode(logger *mlog.Logger, r mlog.Record) (map[string]string, string) {
+	labels := make(map[string]string, len(e.labels)+len(e.labelKeys))
+	for k, v := range e.labels {
+		labels[k] = v
+	}
+
+	var attrs []mlog.Attr
+	if e.labelKeys["level"] {
+		labels["level"] = strings.ToLower(r.Level.String())
+	} else {
+		// the level letter, as written by FormatWriterStructured
+		attrs = append(attrs, *mlog.String("level", r.Level.String()[:1]))
+	}
+	attrs = append(attrs, *mlog.String("msg", r.Message))
+	if r.PC != 0 {
+		file, line := r.Caller()
+		if logger != nil && logger.Flags()&mlog.Lshortfile != 0 {
+			file = filepath.Base(file)
+		}
+		attrs = append(attrs, *mlog.String("caller", file+":"+strconv.Itoa(line)))
+	}
+	r.Attrs(func(attr mlog.Attr) bool {
+		if reservedKeys[attr.Key] {
+			attr.Key = "attr_" + attr.Key
+		}
+		if e.labelKeys[attr.Key] {
+			labels[labelName(attr.Key)] = attr.StringValue()
+		} else {
+			attrs = append(attrs, attr)
+		}
+		return true
+	})
+
+	b := &bytes.Buffer{}
+	if e.opts.JSON {
+		writeJSONLine(b, attrs)
+	} else {
+		for i := range attrs {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(attrs[i].String())
+		}
+	}
+	return labels, b.String()
+}
+
+// push sends b, retrying with exponential backoff on failure, until Close is
+// called.
+func (e *Emitter) push(b *batch) error {
+	keys := make([]string, 0, len(b.streams))
+	for k := range b.streams {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	req := pushRequest{Streams: make([]*stream, 0, len(keys))}
+	for _, k := range keys {
+		req.Streams = append(req.Streams, b.streams[k])
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	if e.opts.Gzip {
+		z := &bytes.Buffer{}
+		zw := gzip.NewWriter(z)
+		if _, err := zw.Write(body); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		body = z.Bytes()
+	}
+
+	backoff := e.opts.MinBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := e.send(body)
+		if err == nil || !retry || attempt >= e.opts.MaxRetries {
+			return err
+		}
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+		case <-e.done:
+			t.Stop()
+			return err
+		}
+		backoff *= 2
+		if backoff > e.opts.MaxBackoff {
+			backoff = e.opts.MaxBackoff
+		}
+	}
+}
+
+// send sends a push request, and returns whether it should be retried on
+// failure.
+func (e *Emitter) send(body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(e.ctx, http.MethodPost, e.pushURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.opts.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if e.opts.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", e.opts.TenantID)
+	}
+
+	resp, err := e.opts.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	err = fmt.Errorf("loki: push failed: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5
+	return retry, err
+}
+
+// labelsKey returns a string identifying a label set.
+func labelsKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[k]))
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+// labelName returns key as a valid Loki label name, with characters other
+// than letters, digits and '_' replaced by '_'.
+func labelName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// writeJSONLine writes attrs as a json object, encoded as by
+// mlog.FormatWriterJSON.
+func writeJSONLine(b *bytes.Buffer, attrs []mlog.Attr) {
+	b.WriteByte('{')
+	for i := range attrs {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.Write(mlog.AppendJSONString(b.AvailableBuffer(), attrs[i].Key))
+		b.WriteByte(':')
+		b.Write(mlog.AppendJSONValue(b.AvailableBuffer(), &attrs[i]))
+	}
+	b.WriteByte('}')
+}
@@ -0,0 +1,240 @@
+package loki
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cactus/mlog"
+	"github.com/dropwhile/assert"
+)
+
+var testTime = time.Date(2016, time.January, 11, 12, 13, 14, 15, time.UTC)
+
+// testServer is a Loki stand-in, that passes each push request to a
+// channel.
+type testServer struct {
+	*httptest.Server
+	pushes chan *http.Request
+	bodies chan pushRequest
+	// status returns the response status for the nth request
+	status func(n int32) int
+	n      atomic.Int32
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	s := &testServer{
+		pushes: make(chan *http.Request, 100),
+		bodies: make(chan pushRequest, 100),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := s.n.Add(1)
+		if s.status != nil {
+			if status := s.status(n); status != http.StatusNoContent {
+				http.Error(w, "failed", status)
+				return
+			}
+		}
+
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			body = zr
+		}
+		var req pushRequest
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.pushes <- r
+		s.bodies <- req
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testServer) next(t *testing.T) (*http.Request, pushRequest) {
+	t.Helper()
+	select {
+	case r := <-s.pushes:
+		return r, <-s.bodies
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+		return nil, pushRequest{}
+	}
+}
+
+func TestEmitterPush(t *testing.T) {
+	s := newTestServer(t)
+	e, err := New(Options{
+		URL:       s.URL + "/",
+		TenantID:  "team1",
+		Labels:    map[string]string{"env": "prod"},
+		LabelKeys: []string{"service", "level"},
+		BatchWait: time.Hour,
+	})
+	assert.Nil(t, err)
+	defer e.Close()
+
+	logger := mlog.NewFormatLogger(io.Discard, 0, e).With(mlog.String("service", "billing"))
+	r := mlog.NewRecord(testTime, mlog.LevelWarn, "one", 0)
+	r.AddAttrs(mlog.Int("n", 1), mlog.String("s", `a "b"`))
+	logger.EmitRecord(r)
+	logger.EmitRecord(mlog.NewRecord(testTime, mlog.LevelWarn, "two", 0))
+	logger.EmitRecord(mlog.NewRecord(testTime, mlog.LevelError, "three", 0))
+	assert.Nil(t, e.Flush())
+
+	req, body := s.next(t)
+	assert.Equal(t, req.URL.Path, PushPath)
+	assert.Equal(t, req.Header.Get("X-Scope-OrgID"), "team1")
+	assert.Equal(t, req.Header.Get("Content-Type"), "application/json")
+	assert.Equal(t, len(body.Streams), 2)
+
+	ts := "1452514394000000015"
+	assert.Equal(t, body.Streams[0].Stream, map[string]string{"env": "prod", "level": "error", "service": "billing"})
+	assert.Equal(t, body.Streams[0].Values, [][2]string{{ts, `msg="three"`}})
+	assert.Equal(t, body.Streams[1].Stream, map[string]string{"env": "prod", "level": "warn", "service": "billing"})
+	assert.Equal(t, body.Streams[1].Values, [][2]string{
+		{ts, `msg="one" n="1" s="a \"b\""`},
+		{ts, `msg="two"`},
+	})
+
+	// nothing to push
+	assert.Nil(t, e.Flush())
+	assert.Equal(t, s.n.Load(), 1)
+}
+
+func TestEmitterJSONGzip(t *testing.T) {
+	s := newTestServer(t)
+	e, err := New(Options{URL: s.URL, JSON: true, Gzip: true, BatchWait: time.Hour})
+	assert.Nil(t, err)
+	defer e.Close()
+
+	logger := mlog.NewFormatLogger(io.Discard, mlog.Lshortfile, e)
+	logger.Infox("hello", mlog.Int("n", 1), mlog.Bool("ok", true), mlog.Duration("d", time.Second),
+		mlog.Err("error", errors.New("oops")), mlog.String("msg", "x"))
+	assert.Nil(t, e.Flush())
+
+	req, body := s.next(t)
+	assert.Equal(t, req.Header.Get("Content-Encoding"), "gzip")
+	// a job label is added without static labels
+	assert.Equal(t, body.Streams[0].Stream, map[string]string{"job": filepath.Base(os.Args[0])})
+	assert.MatchesRegex(t, body.Streams[0].Values[0][1],
+		`^\{"level":"I","msg":"hello","caller":"loki_test.go:\d+","n":1,"ok":true,"d":"1s","error":"oops",`+
+			`"attr_msg":"x"\}$`)
+}
+
+func TestEmitterBatching(t *testing.T) {
+	s := newTestServer(t)
+	// by size
+	e, err := New(Options{URL: s.URL, BatchSize: 20, BatchWait: time.Hour})
+	assert.Nil(t, err)
+	defer e.Close()
+
+	logger := mlog.NewFormatLogger(io.Discard, 0, e)
+	logger.Info("0123456789")
+	logger.Info("0123456789")
+	_, body := s.next(t)
+	assert.Equal(t, len(body.Streams[0].Values), 2)
+
+	// by time
+	e2, err := New(Options{URL: s.URL, BatchWait: 10 * time.Millisecond})
+	assert.Nil(t, err)
+	defer e2.Close()
+	mlog.NewFormatLogger(io.Discard, 0, e2).Info("test")
+	_, body = s.next(t)
+	assert.Equal(t, body.Streams[0].Values[0][1], `level="I" msg="test"`)
+
+	// Close pushes the remaining events
+	e3, err := New(Options{URL: s.URL, BatchWait: time.Hour})
+	assert.Nil(t, err)
+	mlog.NewFormatLogger(io.Discard, 0, e3).Info("closing")
+	assert.Nil(t, e3.Close())
+	_, body = s.next(t)
+	assert.Equal(t, body.Streams[0].Values[0][1], `level="I" msg="closing"`)
+}
+
+func TestEmitterRetry(t *testing.T) {
+	s := newTestServer(t)
+	s.status = func(n int32) int {
+		switch n {
+		case 1:
+			return http.StatusServiceUnavailable
+		case 2:
+			return http.StatusTooManyRequests
+		case 4:
+			return http.StatusBadRequest
+		default:
+			return http.StatusNoContent
+		}
+	}
+
+	e, err := New(Options{URL: s.URL, BatchWait: time.Hour, MinBackoff: time.Millisecond})
+	assert.Nil(t, err)
+	defer e.Close()
+
+	logger := mlog.NewFormatLogger(io.Discard, 0, e)
+	logger.Info("retried")
+	assert.Nil(t, e.Flush())
+	_, body := s.next(t)
+	assert.Equal(t, body.Streams[0].Values[0][1], `level="I" msg="retried"`)
+	assert.Equal(t, s.n.Load(), 3)
+
+	// not retried
+	logger.Info("rejected")
+	err = e.Flush()
+	assert.Equal(t, err.Error(), "loki: push failed: 400 Bad Request: failed")
+	assert.Equal(t, s.n.Load(), 4)
+
+	// retries are limited
+	s.status = func(int32) int { return http.StatusInternalServerError }
+	e.opts.MaxRetries = 2
+	logger.Info("dropped")
+	assert.NotNil(t, e.Flush())
+	assert.Equal(t, s.n.Load(), 7)
+}
+
+func TestEmitterCloseBackoff(t *testing.T) {
+	s := newTestServer(t)
+	s.status = func(int32) int { return http.StatusServiceUnavailable }
+
+	e, err := New(Options{URL: s.URL, BatchWait: 10 * time.Millisecond, MinBackoff: time.Hour})
+	assert.Nil(t, err)
+	assert.Equal(t, e.opts.Client.Timeout, DefaultTimeout)
+
+	mlog.NewFormatLogger(io.Discard, 0, e).Info("failing")
+	for s.n.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Close does not wait out the backoff of the failed push
+	start := time.Now()
+	assert.Nil(t, e.Close())
+	assert.True(t, time.Since(start) < 5*time.Second)
+	assert.Equal(t, s.n.Load(), 1)
+}
+
+func TestNewInvalid(t *testing.T) {
+	_, err := New(Options{})
+	assert.NotNil(t, err)
+}
+
+func TestLabelName(t *testing.T) {
+	assert.Equal(t, labelName("service"), "service")
+	assert.Equal(t, labelName("http.status-code"), "http_status_code")
+	assert.Equal(t, labelName("2fa"), "_2fa")
+}
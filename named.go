@@ -0,0 +1,200 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mlog
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// namedLoggers is the registry of per-name settings, shared by all named
+// Loggers.
+var namedLoggers = &namedRegistry{
+	settings: make(map[string]namedSettings),
+}
+
+// namedSettings are the flags and level configured for a name prefix.
+type namedSettings struct {
+	flags    FlagSet
+	level    Level
+	hasFlags bool
+	hasLevel bool
+	// gen is the registry generation the effective settings were resolved
+	// at
+	gen uint64
+}
+
+// namedNode caches the effective settings for the name of a named Logger,
+// and is shared by its child Loggers created with With. The registry does
+// not reference nodes, so names built at runtime (such as per request) are
+// not retained.
+type namedNode struct {
+	name     string
+	settings atomic.Pointer[namedSettings]
+}
+
+type namedRegistry struct {
+	mu       sync.Mutex
+	settings map[string]namedSettings
+	// gen is incremented on every update, so that nodes know to resolve
+	// their settings again
+	gen atomic.Uint64
+}
+
+// Named returns a new child Logger of l with the given name, that includes a
+// logger="name" field in every log line it emits. If l is itself named, the
+// names are joined with a '.', as in "db.pool".
+//
+// The flags and minimum level of a named Logger can be configured for a
+// whole subtree of names with SetNamedFlags and SetNamedLevel. Otherwise, the
+// flags and minimum level of l are used.
+func (l *Logger) Named(name string) *Logger {
+	if name == "" {
+		return l
+	}
+
+	attrs := l.attrs
+	if l.name != nil {
+		name = l.name.name + "." + name
+		// replace the logger attr of l, which is always first
+		attrs = attrs[1:]
+	}
+
+	bound := make([]*Attr, 0, len(attrs)+1)
+	bound = append(bound, String("logger", name))
+	bound = append(bound, attrs...)
+	n := &namedNode{name: name}
+	n.settings.Store(namedLoggers.resolve(name))
+	return &Logger{core: l.core, attrs: bound, name: n}
+}
+
+// Name returns the name of the Logger, or an empty string if it is not a
+// named Logger.
+func (l *Logger) Name() string {
+	if l.name == nil {
+		return ""
+	}
+	return l.name.name
+}
+
+// SetNamedFlags sets the FlagSet used by named Loggers with the name prefix,
+// or with a name beginning with prefix followed by a '.'. For example, the
+// prefix "db" applies to "db", "db.pool" and "db.migrate". The longest
+// matching prefix applies.
+func SetNamedFlags(prefix string, flags FlagSet) {
+	namedLoggers.update(prefix, func(s *namedSettings) {
+		s.flags = flags
+		s.hasFlags = true
+	})
+}
+
+// SetNamedLevel sets the minimum Level used by named Loggers with the name
+// prefix, or with a name beginning with prefix followed by a '.'. See
+// SetNamedFlags.
+func SetNamedLevel(prefix string, level Level) {
+	namedLoggers.update(prefix, func(s *namedSettings) {
+		s.level = level
+		s.hasLevel = true
+	})
+}
+
+// ResetNamed removes the FlagSet and Level set for prefix with
+// SetNamedFlags and SetNamedLevel.
+func ResetNamed(prefix string) {
+	namedLoggers.update(prefix, func(s *namedSettings) {
+		*s = namedSettings{}
+	})
+}
+
+// load returns the effective settings for n, resolving them again if any
+// settings changed since they were last resolved.
+func (n *namedNode) load() *namedSettings {
+	s := n.settings.Load()
+	if s.gen != namedLoggers.gen.Load() {
+		s = namedLoggers.resolve(n.name)
+		n.settings.Store(s)
+	}
+	return s
+}
+
+// update changes the settings for prefix. Nodes resolve their effective
+// settings again when they are next loaded.
+func (r *namedRegistry) update(prefix string, f func(*namedSettings)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.settings[prefix]
+	f(&s)
+	if s.hasFlags || s.hasLevel {
+		r.settings[prefix] = s
+	} else {
+		delete(r.settings, prefix)
+	}
+	r.gen.Add(1)
+}
+
+// updateFlags atomically sets the flags for name to f applied to its
+// effective flags, as with SetNamedFlags. It returns false, without changing
+// anything, if no flags are set for name or any of its prefixes.
+func (r *namedRegistry) updateFlags(name string, f func(FlagSet) FlagSet) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	eff := r.resolveLocked(name)
+	if !eff.hasFlags {
+		return false
+	}
+	s := r.settings[name]
+	s.flags, s.hasFlags = f(eff.flags), true
+	r.settings[name] = s
+	r.gen.Add(1)
+	return true
+}
+
+// updateLevel is updateFlags, for the level.
+func (r *namedRegistry) updateLevel(name string, f func(Level) Level) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	eff := r.resolveLocked(name)
+	if !eff.hasLevel {
+		return false
+	}
+	s := r.settings[name]
+	s.level, s.hasLevel = f(eff.level), true
+	r.settings[name] = s
+	r.gen.Add(1)
+	return true
+}
+
+// resolve returns the effective settings for name, from the longest matching
+// prefix that sets each of flags and level.
+func (r *namedRegistry) resolve(name string) *namedSettings {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.resolveLocked(name)
+}
+
+// resolveLocked is resolve, with r.mu held.
+func (r *namedRegistry) resolveLocked(name string) *namedSettings {
+	eff := namedSettings{gen: r.gen.Load()}
+	for prefix := name; ; {
+		if s, ok := r.settings[prefix]; ok {
+			if s.hasFlags && !eff.hasFlags {
+				eff.flags, eff.hasFlags = s.flags, true
+			}
+			if s.hasLevel && !eff.hasLevel {
+				eff.level, eff.hasLevel = s.level, true
+			}
+		}
+		i := strings.LastIndexByte(prefix, '.')
+		if i < 0 {
+			break
+		}
+		prefix = prefix[:i]
+	}
+	return &eff
+}
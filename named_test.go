@@ -0,0 +1,121 @@
+package mlog
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dropwhile/assert"
+)
+
+func TestLoggerNamed(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(buf, 0)
+
+	db := logger.Named("db")
+	assert.Equal(t, db.Name(), "db")
+	db.Infox("test")
+	assert.Equal(t, buf.String(), `msg="test" logger="db"`+"\n")
+
+	// nested names replace the logger field, and keep other bound attrs
+	buf.Truncate(0)
+	pool := db.With(String("x", "y")).Named("pool")
+	assert.Equal(t, pool.Name(), "db.pool")
+	pool.Infox("test")
+	pool.With(String("z", "1")).Infox("test")
+	assert.Equal(t, buf.String(),
+		`msg="test" logger="db.pool" x="y"`+"\n"+
+			`msg="test" logger="db.pool" x="y" z="1"`+"\n")
+
+	assert.Equal(t, logger.Named(""), logger)
+	assert.Equal(t, logger.Name(), "")
+}
+
+func TestSetNamedFlags(t *testing.T) {
+	defer ResetNamed("db")
+	defer ResetNamed("db.migrate")
+
+	buf := &bytes.Buffer{}
+	logger := New(buf, 0)
+	pool := logger.Named("db").Named("pool")
+	migrate := logger.Named("db.migrate")
+	other := logger.Named("dbx")
+
+	SetNamedFlags("db", Llevel|Ldebug)
+	for _, l := range []*Logger{pool, migrate, other, logger} {
+		l.Debugx("test")
+	}
+	assert.Equal(t, buf.String(),
+		`level="D" msg="test" logger="db.pool"`+"\n"+
+			`level="D" msg="test" logger="db.migrate"`+"\n")
+
+	// the longest prefix applies, and also applies to Loggers created later
+	buf.Truncate(0)
+	SetNamedFlags("db.migrate", 0)
+	migrate.Debugx("test")
+	logger.Named("db.migrate.step").Debugx("test")
+	pool.With(String("x", "y")).Debugx("test")
+	assert.Equal(t, buf.String(), `level="D" msg="test" logger="db.pool" x="y"`+"\n")
+
+	// the parent flags apply after reset
+	buf.Truncate(0)
+	ResetNamed("db")
+	pool.Debugx("test")
+	pool.Infox("test")
+	assert.Equal(t, buf.String(), `msg="test" logger="db.pool"`+"\n")
+}
+
+func TestSetNamedLevel(t *testing.T) {
+	defer ResetNamed("db")
+
+	buf := &bytes.Buffer{}
+	logger := New(buf, 0)
+	pool := logger.Named("db.pool")
+
+	SetNamedLevel("db", LevelError)
+	assert.Equal(t, pool.Level(), LevelError)
+	assert.Equal(t, logger.Level(), LevelInfo)
+	pool.Warnx("test")
+	pool.Errorx("test")
+	logger.Warnx("test")
+	assert.Equal(t, buf.String(),
+		`msg="test" logger="db.pool"`+"\n"+`msg="test"`+"\n")
+
+	// the flags are not overridden by a level
+	assert.Equal(t, pool.Flags(), logger.Flags())
+}
+
+func TestUpdateNamedFlags(t *testing.T) {
+	defer ResetNamed("db")
+	defer ResetNamed("db.pool")
+
+	logger := New(io.Discard, Llevel)
+	pool := logger.Named("db").Named("pool")
+	pool.UpdateFlags(func(f FlagSet) FlagSet { return f | Ldebug })
+	assert.Equal(t, logger.Flags(), Ldebug|Llevel)
+
+	SetNamedFlags("db", Lshortfile)
+	pool.UpdateFlags(func(f FlagSet) FlagSet { return f | Ldebug })
+	assert.Equal(t, pool.Flags(), Ldebug|Lshortfile)
+	assert.Equal(t, logger.Named("db").Flags(), Lshortfile)
+	assert.Equal(t, logger.Flags(), Ldebug|Llevel)
+
+	pool.UpdateLevel(func(Level) Level { return LevelError })
+	assert.Equal(t, pool.Level(), LevelError)
+	assert.Equal(t, logger.Level(), LevelError)
+}
+
+func TestNamedNotRetained(t *testing.T) {
+	var collected atomic.Bool
+	func() {
+		logger := New(&bytes.Buffer{}, 0).Named("request-1234")
+		runtime.SetFinalizer(logger.name, func(*namedNode) { collected.Store(true) })
+	}()
+
+	waitFor(t, func() bool {
+		runtime.GC()
+		return collected.Load()
+	})
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mlog
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// netDialTimeout is the timeout used by a NetWriter to connect.
+	netDialTimeout = 5 * time.Second
+	// netWriteTimeout is the timeout of each write by a NetWriter, so that
+	// an unresponsive peer does not block logging indefinitely.
+	netWriteTimeout = 5 * time.Second
+	// netMinBackoff and netMaxBackoff bound the delay between attempts to
+	// reconnect.
+	netMinBackoff = 100 * time.Millisecond
+	netMaxBackoff = 30 * time.Second
+)
+
+// ErrNotConnected is returned when writing to a NetWriter that has lost its
+// connection, until it has reconnected.
+var ErrNotConnected = errors.New("mlog: not connected")
+
+// NetWriter is an io.WriteCloser that writes to a network connection, such as
+// a tcp, udp or unix socket. If a write fails, the connection is closed, and
+// reconnected in the background with exponential backoff, so that a
+// restarted log collector does not lose all subsequent output. Writes fail
+// fast with ErrNotConnected until then. A failed write is not resent, so a
+// partially written line or frame is never repeated. It is safe for
+// concurrent use.
+//
+// With a datagram network (udp, unixgram), each Write is sent as one datagram,
+// so it should be used as the output of a Logger, and not shared with other
+// writers.
+type NetWriter struct {
+	network string
+	address string
+
+	mu      sync.Mutex
+	conn    net.Conn
+	dialing bool
+	closed  bool
+
+	// ctx is cancelled by Close, to stop reconnecting
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewNetWriter connects to address on the named network, as with net.Dial,
+// and returns a NetWriter writing to it.
+func NewNetWriter(network, address string) (*NetWriter, error) {
+	w := &NetWriter{network: network, address: address}
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	conn, err := w.dial()
+	if err != nil {
+		w.cancel()
+		return nil, err
+	}
+	w.conn = conn
+	return w, nil
+}
+
+func (w *NetWriter) dial() (net.Conn, error) {
+	d := net.Dialer{Timeout: netDialTimeout}
+	return d.DialContext(w.ctx, w.network, w.address)
+}
+
+// Write writes p to the connection. If there is no connection, it returns
+// ErrNotConnected without blocking.
+func (w *NetWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrWriterClosed
+	}
+	if w.conn == nil {
+		return 0, ErrNotConnected
+	}
+
+	if err := w.conn.SetWriteDeadline(time.Now().Add(netWriteTimeout)); err != nil {
+		w.disconnect()
+		return 0, err
+	}
+	n, err := w.conn.Write(p)
+	if err != nil {
+		w.disconnect()
+	}
+	return n, err
+}
+
+// disconnect closes the connection, and starts reconnecting in the
+// background. w.mu must be held.
+func (w *NetWriter) disconnect() {
+	w.conn.Close()
+	w.conn = nil
+	if !w.dialing {
+		w.dialing = true
+		w.wg.Add(1)
+		go w.redial()
+	}
+}
+
+// redial reconnects, with exponential backoff between attempts, until it
+// succeeds or the NetWriter is closed.
+func (w *NetWriter) redial() {
+	defer w.wg.Done()
+
+	backoff := netMinBackoff
+	for {
+		conn, err := w.dial()
+		if err == nil {
+			w.mu.Lock()
+			w.dialing = false
+			if w.closed {
+				conn.Close()
+			} else {
+				w.conn = conn
+			}
+			w.mu.Unlock()
+			return
+		}
+
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+		case <-w.ctx.Done():
+			t.Stop()
+			return
+		}
+		backoff = min(2*backoff, netMaxBackoff)
+	}
+}
+
+// Close closes the connection, and stops any reconnection attempt. Writes
+// after Close return ErrWriterClosed.
+func (w *NetWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.cancel()
+	var err error
+	if w.conn != nil {
+		err = w.conn.Close()
+	}
+	w.mu.Unlock()
+
+	w.wg.Wait()
+	return err
+}
@@ -0,0 +1,122 @@
+package mlog
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dropwhile/assert"
+)
+
+func TestNetWriterTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+
+	w, err := NewNetWriter("tcp", ln.Addr().String())
+	assert.Nil(t, err)
+	defer w.Close()
+
+	conn, err := ln.Accept()
+	assert.Nil(t, err)
+	logger := New(w, 0)
+	logger.Info("first")
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, line, `msg="first"`+"\n")
+
+	// the server drops the connection; the writer reconnects in the
+	// background. The first write after the drop may be accepted by the dead
+	// connection, so keep writing until a new connection is made.
+	conn.Close()
+	accepted := make(chan net.Conn)
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+	var conn2 net.Conn
+	for conn2 == nil {
+		logger.Info("dropped")
+		select {
+		case conn2 = <-accepted:
+		case <-time.After(time.Millisecond):
+		}
+	}
+	// writes fail fast until the new connection is in use
+	for {
+		_, err := w.Write([]byte(`msg="second"` + "\n"))
+		if err == nil {
+			break
+		}
+		assert.Error(t, err, ErrNotConnected)
+		time.Sleep(time.Millisecond)
+	}
+	defer conn2.Close()
+	line, err = bufio.NewReader(conn2).ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, line, `msg="second"`+"\n")
+
+	assert.Nil(t, w.Close())
+	_, err = w.Write([]byte("x\n"))
+	assert.Error(t, err, ErrWriterClosed)
+}
+
+func TestNetWriterUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer pc.Close()
+
+	w, err := NewNetWriter("udp", pc.LocalAddr().String())
+	assert.Nil(t, err)
+	defer w.Close()
+
+	New(w, 0).Infox("test", Int("a", 1))
+	buf := make([]byte, 1024)
+	n, _, err := pc.ReadFrom(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, string(buf[:n]), `msg="test" a="1"`+"\n")
+}
+
+func TestNetWriterDialError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+
+	_, err = NewNetWriter("tcp", addr)
+	assert.NotNil(t, err)
+}
+
+func TestNetWriterNotConnected(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	w, err := NewNetWriter("tcp", ln.Addr().String())
+	assert.Nil(t, err)
+	conn, err := ln.Accept()
+	assert.Nil(t, err)
+
+	// the endpoint goes away; once the writer notices, writes fail fast
+	// while it retries in the background
+	ln.Close()
+	conn.Close()
+	for {
+		if _, err := w.Write([]byte("x\n")); err != nil {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		_, err := w.Write([]byte("x\n"))
+		assert.Error(t, err, ErrNotConnected)
+	}
+	assert.True(t, time.Since(start) < time.Second, "writes did not fail fast")
+
+	// Close stops the reconnection attempts
+	assert.Nil(t, w.Close())
+	_, err = w.Write([]byte("x\n"))
+	assert.Error(t, err, ErrWriterClosed)
+}
@@ -0,0 +1,199 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mlog
+
+import (
+	"sort"
+	"time"
+)
+
+// RecordEmitter is the interface implemented by Emitters that format a
+// single Record per log event. The time and caller of the event are
+// captured once by the Logger, instead of being resolved by each Emitter.
+//
+// A RecordEmitter can be used as an Emitter with WrapRecordEmitter.
+type RecordEmitter interface {
+	EmitRecord(logger *Logger, r Record)
+}
+
+// A Record holds the information about a log event.
+type Record struct {
+	// Time is the time the event was logged.
+	// A zero Time is omitted from the output.
+	Time time.Time
+	// Message is the log message.
+	Message string
+	// Level is the log level.
+	Level Level
+	// PC is the program counter of the caller that logged the event. It is
+	// only captured if the Logger has the Llongfile or Lshortfile flag.
+	// A zero PC is omitted from the output.
+	PC uintptr
+
+	// Attrs are stored by value, and inline for the common case of only a
+	// few Attrs, to avoid allocations. Map elements are normalized to Attrs.
+	front  [nAttrsInline]Attr
+	nFront int
+	back   []Attr
+}
+
+// nAttrsInline is the number of Attrs stored inline in a Record.
+const nAttrsInline = 5
+
+// NewRecord creates a Record from the given arguments. Use Record.AddAttrs to
+// add Attrs to the Record.
+func NewRecord(t time.Time, level Level, message string, pc uintptr) Record {
+	return Record{
+		Time:    t,
+		Message: message,
+		Level:   level,
+		PC:      pc,
+	}
+}
+
+// AddAttrs appends copies of the given Attrs to the Record's list of Attrs.
+// Nil Attrs are skipped.
+func (r *Record) AddAttrs(attrs ...*Attr) {
+	for _, attr := range attrs {
+		if attr != nil {
+			r.addAttr(attr)
+		}
+	}
+}
+
+// addAttr appends a copy of attr to the Record's list of Attrs.
+func (r *Record) addAttr(attr *Attr) {
+	if r.nFront < len(r.front) {
+		r.front[r.nFront] = *attr
+		r.nFront++
+		return
+	}
+	r.back = append(r.back, *attr)
+}
+
+// addMap appends the elements of m to the Record's list of Attrs, sorted by
+// key if sorted is true.
+func (r *Record) addMap(m Map, sorted bool) {
+	if len(m) == 0 {
+		return
+	}
+	if sorted {
+		keys := m.Keys()
+		sort.Strings(keys)
+		for _, k := range keys {
+			r.addAttr(&Attr{Key: k, Value: m[k]})
+		}
+		return
+	}
+	for k, v := range m {
+		r.addAttr(&Attr{Key: k, Value: v})
+	}
+}
+
+// NumAttrs returns the number of Attrs in the Record.
+func (r Record) NumAttrs() int {
+	return r.nFront + len(r.back)
+}
+
+// Attrs calls f on each Attr in the Record, in order.
+// Iteration stops if f returns false.
+func (r Record) Attrs(f func(attr Attr) bool) {
+	for i := 0; i < r.nFront; i++ {
+		if !f(r.front[i]) {
+			return
+		}
+	}
+	for _, attr := range r.back {
+		if !f(attr) {
+			return
+		}
+	}
+}
+
+// attr returns a pointer to the i'th Attr in the Record.
+func (r *Record) attr(i int) *Attr {
+	if i < r.nFront {
+		return &r.front[i]
+	}
+	return &r.back[i-r.nFront]
+}
+
+// attrPtrs returns pointers to copies of the Attrs in the Record, with
+// Value set, for Emitters that are not a RecordEmitter.
+func (r Record) attrPtrs() []*Attr {
+	attrs := make([]*Attr, 0, r.NumAttrs())
+	r.Attrs(func(attr Attr) bool {
+		attr.Value = attr.Any()
+		attrs = append(attrs, &attr)
+		return true
+	})
+	return attrs
+}
+
+// Caller returns the file path and line number of the Record PC, or "???"
+// and 0 if the PC is zero or unknown.
+func (r Record) Caller() (file string, line int) {
+	if r.PC == 0 {
+		return "???", 0
+	}
+	return callerFileLine(r.PC, false)
+}
+
+// newRecord creates a Record for an event logged by l. The caller is
+// resolved skip frames above the caller of newRecord, with the same skip
+// semantics as runtime.Caller.
+func (l *Logger) newRecord(level Level, message string, skip int) Record {
+	r := Record{Time: time.Now(), Message: message, Level: level}
+	if l.Flags()&(Lshortfile|Llongfile) != 0 {
+		r.PC = callerPC(skip + 1)
+	}
+	return r
+}
+
+// EmitAttrsRecord adapts a call to the EmitAttrs method of a RecordEmitter,
+// for RecordEmitters that also implement Emitter, as in:
+//
+//	func (e *MyEmitter) EmitAttrs(logger *mlog.Logger, level int, message string, extra ...*mlog.Attr) {
+//	    mlog.EmitAttrsRecord(e, logger, level, message, extra)
+//	}
+//
+// The caller is resolved at the same depth as Emitters calling
+// runtime.Caller(3) from EmitAttrs.
+func EmitAttrsRecord(re RecordEmitter, logger *Logger, level int, message string, extra []*Attr) {
+	r := logger.newRecord(LevelFromEmitter(level), message, 4)
+	r.AddAttrs(extra...)
+	re.EmitRecord(logger, r)
+}
+
+// EmitMapRecord adapts a call to the Emit method of a RecordEmitter, as
+// EmitAttrsRecord does for EmitAttrs. The caller is resolved at the same
+// depth as Emitters calling runtime.Caller(3) from Emit.
+func EmitMapRecord(re RecordEmitter, logger *Logger, level int, message string, extra Map) {
+	r := logger.newRecord(LevelFromEmitter(level), message, 4)
+	r.addMap(extra, logger.Flags()&Lsort != 0)
+	re.EmitRecord(logger, r)
+}
+
+// recordEmitterAdapter adapts a RecordEmitter to the Emitter interface.
+type recordEmitterAdapter struct {
+	RecordEmitter
+}
+
+// WrapRecordEmitter returns an Emitter that passes every log event to re as
+// a Record, so that re can be used with NewFormatLogger and SetEmitter.
+func WrapRecordEmitter(re RecordEmitter) Emitter {
+	if e, ok := re.(Emitter); ok {
+		return e
+	}
+	return &recordEmitterAdapter{re}
+}
+
+func (a *recordEmitterAdapter) Emit(logger *Logger, level int, message string, extra Map) {
+	EmitMapRecord(a.RecordEmitter, logger, level, message, extra)
+}
+
+func (a *recordEmitterAdapter) EmitAttrs(logger *Logger, level int, message string, extra ...*Attr) {
+	EmitAttrsRecord(a.RecordEmitter, logger, level, message, extra)
+}
@@ -0,0 +1,89 @@
+package mlog
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/dropwhile/assert"
+)
+
+type testRecordEmitter struct {
+	records []Record
+}
+
+func (e *testRecordEmitter) EmitRecord(logger *Logger, r Record) {
+	e.records = append(e.records, r)
+}
+
+func TestRecordEmitter(t *testing.T) {
+	re := &testRecordEmitter{}
+	logger := NewFormatLogger(io.Discard, Lsort|Lshortfile|Ldebug, WrapRecordEmitter(re))
+
+	tnow := time.Now()
+	logger.With(A("a", "b")).Debugx("test", A("x", "y"), nil)
+	logger.Infom("test", Map{"y": "z", "x": "y"})
+	logger.EmitRecord(NewRecord(time.Time{}, 1, "test", 0))
+	assert.Equal(t, len(re.records), 3)
+
+	r := re.records[0]
+	assert.Equal(t, r.Level, LevelDebug)
+	assert.Equal(t, r.Message, "test")
+	assert.True(t, r.Time.Sub(tnow) < 2*time.Second, "Time not even close")
+	file, _ := r.Caller()
+	assert.MatchesRegex(t, file, `record_test.go$`)
+
+	keys := []string{}
+	r.Attrs(func(attr Attr) bool {
+		keys = append(keys, attr.Key)
+		return true
+	})
+	assert.Equal(t, keys, []string{"a", "x"})
+
+	r = re.records[1]
+	assert.Equal(t, r.NumAttrs(), 2)
+	keys = keys[:0]
+	r.Attrs(func(attr Attr) bool {
+		keys = append(keys, attr.Key)
+		return true
+	})
+	assert.Equal(t, keys, []string{"x", "y"})
+
+	r = re.records[2]
+	assert.True(t, r.Time.IsZero(), "expected zero time")
+	assert.Equal(t, r.PC, uintptr(0))
+}
+
+type testCallerEmitter struct {
+	files []string
+}
+
+func (e *testCallerEmitter) Emit(logger *Logger, level int, message string, extra Map) {
+	_, file, _, _ := runtime.Caller(3)
+	e.files = append(e.files, file)
+}
+
+func (e *testCallerEmitter) EmitAttrs(logger *Logger, level int, message string, extra ...*Attr) {
+	_, file, _, _ := runtime.Caller(3)
+	e.files = append(e.files, file)
+}
+
+func TestRecordLegacyEmitterCaller(t *testing.T) {
+	e := &testCallerEmitter{}
+	logger := NewFormatLogger(io.Discard, 0, e)
+	logger.Info("test")
+	logger.Infox("test")
+	logger.Infom("test", nil)
+	for _, file := range e.files {
+		assert.MatchesRegex(t, file, `record_test.go$`)
+	}
+}
+
+func TestRecordZeroTime(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(buf, Lstd)
+	logger.EmitRecord(NewRecord(time.Time{}, 0, "test", 0))
+	assert.Equal(t, buf.String(), "level=\"I\" msg=\"test\"\n")
+}
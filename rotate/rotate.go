@@ -0,0 +1,434 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Package rotate provides an io.Writer that writes to a file, rotating it by
+// size and/or on a time interval, for use as the output of an mlog.Logger.
+//
+// Example usage:
+//
+//	w, err := rotate.New("/var/log/app.log", rotate.Options{
+//	    MaxSize:    100 << 20,
+//	    Interval:   rotate.Daily,
+//	    MaxBackups: 7,
+//	    Compress:   true,
+//	})
+//	if err != nil {
+//	    // handle error
+//	}
+//	defer w.Close()
+//	logger := mlog.New(w, mlog.Lstd)
+//
+// Rotated files are renamed to the file name with the rotation time inserted
+// before the extension, as in "app-2006-01-02T15-04-05.000.log", and are
+// gzipped (adding a ".gz" suffix) if Compress is set.
+package rotate
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Common rotation intervals.
+const (
+	Hourly = time.Hour
+	Daily  = 24 * time.Hour
+)
+
+// backupTimeFormat is the format of the rotation time in backup file names.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// Options configures a Writer.
+type Options struct {
+	// MaxSize is the size in bytes at which the file is rotated. Zero
+	// disables rotation by size.
+	MaxSize int64
+	// Interval rotates the file when the current time crosses into a new
+	// interval, such as Hourly or Daily. Intervals are aligned to UTC. Zero
+	// disables rotation by time.
+	Interval time.Duration
+	// MaxBackups is the number of rotated files to keep. Zero keeps all of
+	// them (subject to MaxAge).
+	MaxBackups int
+	// MaxAge is how long to keep rotated files, based on the time in their
+	// name. Zero keeps them regardless of age (subject to MaxBackups).
+	MaxAge time.Duration
+	// Compress gzips rotated files in the background.
+	Compress bool
+	// Mode is the permission bits used to create files. Defaults to 0o644.
+	Mode os.FileMode
+}
+
+// Writer is an io.WriteCloser that writes to a file, and rotates it as
+// configured by its Options. It is safe for concurrent use.
+type Writer struct {
+	path string
+	opts Options
+
+	mu     sync.Mutex
+	file   *os.File // nil if reopening the file after a rotation failed
+	size   int64
+	next   time.Time // next interval rotation, if Interval is set
+	closed bool
+
+	// millCh signals the mill goroutine, which compresses and deletes
+	// rotated files
+	millCh chan struct{}
+	wg     sync.WaitGroup
+
+	now    func() time.Time
+	rename func(oldpath, newpath string) error
+}
+
+// New opens (or creates) the file at path for appending, and returns a Writer
+// for it. Any missing parent directories are created.
+func New(path string, opts Options) (*Writer, error) {
+	return newWriter(path, opts, time.Now)
+}
+
+func newWriter(path string, opts Options, now func() time.Time) (*Writer, error) {
+	if opts.Mode == 0 {
+		opts.Mode = 0o644
+	}
+
+	w := &Writer{
+		path:   path,
+		opts:   opts,
+		millCh: make(chan struct{}, 1),
+		now:    now,
+		rename: os.Rename,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	w.wg.Add(1)
+	go w.mill()
+	// clean up after any previous run
+	w.signalMill()
+	return w, nil
+}
+
+// Write writes b to the file, rotating it first if required. If the rotation
+// fails, b is still written to the current file, and the rotation is retried
+// on the next Write.
+func (w *Writer) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	if w.file != nil && w.shouldRotate(int64(len(b))) {
+		// on failure, rotate reopens the file if it can
+		_ = w.rotate()
+	}
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file, renames it, and opens a new file, as if
+// a rotation were triggered. This can be used to rotate on a signal, for
+// example.
+func (w *Writer) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+	return w.rotate()
+}
+
+// Sync commits the current contents of the file to stable storage.
+func (w *Writer) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+// Close closes the file, and waits for any background compression to finish.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+	}
+	close(w.millCh)
+	w.mu.Unlock()
+
+	w.wg.Wait()
+	return err
+}
+
+func (w *Writer) shouldRotate(n int64) bool {
+	// never rotate an empty file by size, or a single large write would
+	// rotate on every call
+	if w.opts.MaxSize > 0 && w.size > 0 && w.size+n > w.opts.MaxSize {
+		return true
+	}
+	if w.opts.Interval > 0 && !w.now().Before(w.next) {
+		return true
+	}
+	return false
+}
+
+// open opens the file at w.path for appending.
+func (w *Writer) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, w.opts.Mode)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	w.file = f
+	w.size = info.Size()
+	if w.opts.Interval > 0 {
+		// an existing file is rotated at the end of the interval it was last
+		// written in, so a stale file is rotated on the first write
+		last := w.now()
+		if w.size > 0 && info.ModTime().Before(last) {
+			last = info.ModTime()
+		}
+		w.next = last.Truncate(w.opts.Interval).Add(w.opts.Interval)
+	}
+	return nil
+}
+
+// rotate renames the current file to a backup name, and opens a new file.
+// If renaming fails, the current file is reopened, keeping the rotation due
+// so that it is retried. If no file can be opened, w.file is left nil.
+// w.mu must be held.
+func (w *Writer) rotate() error {
+	if w.file != nil {
+		// the descriptor is released even if Close returns an error
+		err := w.file.Close()
+		w.file = nil
+		if err != nil {
+			return errors.Join(err, w.reopen())
+		}
+	}
+	if err := w.rename(w.path, w.backupName()); err != nil {
+		return errors.Join(err, w.reopen())
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.signalMill()
+	return nil
+}
+
+// reopen reopens the file at w.path after a failed rotation, without
+// changing when the next interval rotation is due.
+func (w *Writer) reopen() error {
+	next := w.next
+	err := w.open()
+	w.next = next
+	return err
+}
+
+// backupName returns an unused name for the backup file of a rotation at the
+// current time.
+func (w *Writer) backupName() string {
+	prefix, ext := w.nameParts()
+	t := w.now().UTC()
+	for {
+		name := prefix + t.Format(backupTimeFormat) + ext
+		if !exists(name) && !exists(name+".gz") {
+			return name
+		}
+		// rotated more than once in the same millisecond
+		t = t.Add(time.Millisecond)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// nameParts returns the backup file name prefix (including the directory),
+// and the file extension.
+func (w *Writer) nameParts() (string, string) {
+	ext := filepath.Ext(w.path)
+	return strings.TrimSuffix(w.path, ext) + "-", ext
+}
+
+func (w *Writer) signalMill() {
+	select {
+	case w.millCh <- struct{}{}:
+	default:
+		// already signalled
+	}
+}
+
+func (w *Writer) mill() {
+	defer w.wg.Done()
+	for range w.millCh {
+		// errors are ignored; the mill runs again on the next rotation
+		_ = w.millOnce()
+	}
+}
+
+// backup is a rotated file.
+type backup struct {
+	path string
+	t    time.Time
+}
+
+// millOnce compresses and removes backups, as configured.
+func (w *Writer) millOnce() error {
+	backups, err := w.backups()
+	if err != nil {
+		return err
+	}
+
+	var remove []backup
+	if w.opts.MaxBackups > 0 && len(backups) > w.opts.MaxBackups {
+		remove = append(remove, backups[w.opts.MaxBackups:]...)
+		backups = backups[:w.opts.MaxBackups]
+	}
+	if w.opts.MaxAge > 0 {
+		cutoff := w.now().Add(-w.opts.MaxAge)
+		keep := backups[:0]
+		for _, b := range backups {
+			if b.t.Before(cutoff) {
+				remove = append(remove, b)
+			} else {
+				keep = append(keep, b)
+			}
+		}
+		backups = keep
+	}
+
+	var errs []error
+	for _, b := range remove {
+		if err := os.Remove(b.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	if w.opts.Compress {
+		for _, b := range backups {
+			if strings.HasSuffix(b.path, ".gz") {
+				continue
+			}
+			if err := compressFile(b.path, w.opts.Mode); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// backups returns the rotated files, newest first.
+func (w *Writer) backups() ([]backup, error) {
+	entries, err := os.ReadDir(filepath.Dir(w.path))
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, ext := w.nameParts()
+	prefix = filepath.Base(prefix)
+
+	var backups []backup
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		ts := strings.TrimPrefix(name, prefix)
+		ts = strings.TrimSuffix(ts, ".gz")
+		if !strings.HasSuffix(ts, ext) {
+			continue
+		}
+		t, err := time.Parse(backupTimeFormat, strings.TrimSuffix(ts, ext))
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{
+			path: filepath.Join(filepath.Dir(w.path), name),
+			t:    t,
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].t.After(backups[j].t)
+	})
+	return backups, nil
+}
+
+// compressFile gzips the file at path to path.gz, then removes path.
+func compressFile(path string, mode os.FileMode) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// write to a temporary name, so a partial file is never mistaken for a
+	// complete backup
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			dst.Close()
+			os.Remove(tmp)
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, path+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
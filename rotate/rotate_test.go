@@ -0,0 +1,273 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dropwhile/assert"
+)
+
+// testClock is a settable clock, safe for use by the mill goroutine.
+type testClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *testClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *testClock) add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func newTestClock() *testClock {
+	return &testClock{t: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)}
+}
+
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	return string(b)
+}
+
+func TestWriterRotateSize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	clock := newTestClock()
+	w, err := newWriter(path, Options{MaxSize: 11}, clock.now)
+	assert.Nil(t, err)
+
+	w.Write([]byte("12345\n"))
+	w.Write([]byte("6789\n"))
+	// would exceed MaxSize, so rotates first
+	w.Write([]byte("abc\n"))
+	clock.add(time.Second)
+	// larger than MaxSize, so rotates first, and is then written to the
+	// empty file
+	w.Write([]byte("too large for a file\n"))
+	clock.add(time.Second)
+	w.Write([]byte("def\n"))
+	assert.Nil(t, w.Close())
+
+	assert.Equal(t, listDir(t, dir), []string{
+		"app-2023-01-02T03-04-05.000.log",
+		"app-2023-01-02T03-04-06.000.log",
+		"app-2023-01-02T03-04-07.000.log",
+		"app.log",
+	})
+	assert.Equal(t, readFile(t, filepath.Join(dir, "app-2023-01-02T03-04-05.000.log")), "12345\n6789\n")
+	assert.Equal(t, readFile(t, filepath.Join(dir, "app-2023-01-02T03-04-06.000.log")), "abc\n")
+	assert.Equal(t, readFile(t, filepath.Join(dir, "app-2023-01-02T03-04-07.000.log")), "too large for a file\n")
+	assert.Equal(t, readFile(t, path), "def\n")
+}
+
+func TestWriterRotateInterval(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	clock := newTestClock()
+	w, err := newWriter(path, Options{Interval: Hourly}, clock.now)
+	assert.Nil(t, err)
+
+	w.Write([]byte("first\n"))
+	clock.add(30 * time.Minute)
+	w.Write([]byte("second\n"))
+	// crosses into 04:00
+	clock.add(30 * time.Minute)
+	w.Write([]byte("third\n"))
+	assert.Nil(t, w.Close())
+
+	assert.Equal(t, listDir(t, dir), []string{
+		"app-2023-01-02T04-04-05.000.log",
+		"app.log",
+	})
+	assert.Equal(t, readFile(t, filepath.Join(dir, "app-2023-01-02T04-04-05.000.log")), "first\nsecond\n")
+	assert.Equal(t, readFile(t, path), "third\n")
+}
+
+func TestWriterRotateSameTime(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	clock := newTestClock()
+	w, err := newWriter(path, Options{}, clock.now)
+	assert.Nil(t, err)
+
+	w.Write([]byte("first\n"))
+	assert.Nil(t, w.Rotate())
+	w.Write([]byte("second\n"))
+	assert.Nil(t, w.Rotate())
+	assert.Nil(t, w.Close())
+
+	assert.Equal(t, listDir(t, dir), []string{
+		"app-2023-01-02T03-04-05.000.log",
+		"app-2023-01-02T03-04-05.001.log",
+		"app.log",
+	})
+	assert.Equal(t, readFile(t, filepath.Join(dir, "app-2023-01-02T03-04-05.001.log")), "second\n")
+}
+
+func TestWriterMaxBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	clock := newTestClock()
+	w, err := newWriter(path, Options{MaxBackups: 2}, clock.now)
+	assert.Nil(t, err)
+
+	for i := 0; i < 4; i++ {
+		w.Write([]byte("line\n"))
+		assert.Nil(t, w.Rotate())
+		clock.add(time.Second)
+	}
+	assert.Nil(t, w.Close())
+
+	// the mill runs in the background, so run it again to be sure it has
+	// seen the final rotation
+	assert.Nil(t, w.millOnce())
+	assert.Equal(t, listDir(t, dir), []string{
+		"app-2023-01-02T03-04-07.000.log",
+		"app-2023-01-02T03-04-08.000.log",
+		"app.log",
+	})
+}
+
+func TestWriterMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	clock := newTestClock()
+	w, err := newWriter(path, Options{MaxAge: 24 * time.Hour}, clock.now)
+	assert.Nil(t, err)
+
+	for i := 0; i < 3; i++ {
+		w.Write([]byte("line\n"))
+		assert.Nil(t, w.Rotate())
+		clock.add(12 * time.Hour)
+	}
+	assert.Nil(t, w.Close())
+
+	assert.Nil(t, w.millOnce())
+	assert.Equal(t, listDir(t, dir), []string{
+		"app-2023-01-02T15-04-05.000.log",
+		"app-2023-01-03T03-04-05.000.log",
+		"app.log",
+	})
+}
+
+func TestWriterCompress(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	clock := newTestClock()
+	w, err := newWriter(path, Options{Compress: true}, clock.now)
+	assert.Nil(t, err)
+
+	w.Write([]byte("compress me\n"))
+	assert.Nil(t, w.Rotate())
+	assert.Nil(t, w.Close())
+
+	assert.Nil(t, w.millOnce())
+	assert.Equal(t, listDir(t, dir), []string{
+		"app-2023-01-02T03-04-05.000.log.gz",
+		"app.log",
+	})
+
+	f, err := os.Open(filepath.Join(dir, "app-2023-01-02T03-04-05.000.log.gz"))
+	assert.Nil(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	assert.Nil(t, err)
+	b, err := io.ReadAll(gz)
+	assert.Nil(t, err)
+	assert.Equal(t, string(b), "compress me\n")
+}
+
+func TestWriterClosed(t *testing.T) {
+	w, err := New(filepath.Join(t.TempDir(), "sub", "app.log"), Options{})
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+	assert.Nil(t, w.Close())
+
+	_, err = w.Write([]byte("line\n"))
+	assert.Error(t, err, os.ErrClosed)
+	assert.Error(t, w.Rotate(), os.ErrClosed)
+}
+
+func TestWriterRotateRenameError(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	clock := newTestClock()
+	w, err := newWriter(path, Options{MaxSize: 6}, clock.now)
+	assert.Nil(t, err)
+	errRename := errors.New("rename failed")
+	w.rename = func(oldpath, newpath string) error { return errRename }
+
+	w.Write([]byte("first\n"))
+	// the rotation fails, so the file is reopened and written to
+	_, err = w.Write([]byte("second\n"))
+	assert.Nil(t, err)
+	assert.Error(t, w.Rotate(), errRename)
+	assert.Equal(t, readFile(t, path), "first\nsecond\n")
+
+	// the rotation is retried on the next write
+	w.rename = os.Rename
+	clock.add(time.Second)
+	w.Write([]byte("third\n"))
+	assert.Nil(t, w.Close())
+
+	assert.Equal(t, listDir(t, dir), []string{
+		"app-2023-01-02T03-04-06.000.log",
+		"app.log",
+	})
+	assert.Equal(t, readFile(t, filepath.Join(dir, "app-2023-01-02T03-04-06.000.log")), "first\nsecond\n")
+	assert.Equal(t, readFile(t, path), "third\n")
+}
+
+func TestWriterRotateOpenError(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	clock := newTestClock()
+	w, err := newWriter(path, Options{}, clock.now)
+	assert.Nil(t, err)
+	// after renaming, a directory in the way of the new file stops it being
+	// opened
+	w.rename = func(oldpath, newpath string) error {
+		if err := os.Rename(oldpath, newpath); err != nil {
+			return err
+		}
+		return os.Mkdir(oldpath, 0o755)
+	}
+
+	w.Write([]byte("first\n"))
+	assert.NotNil(t, w.Rotate())
+	_, err = w.Write([]byte("lost\n"))
+	assert.NotNil(t, err)
+
+	// the file is reopened once it can be
+	assert.Nil(t, os.Remove(path))
+	_, err = w.Write([]byte("second\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+	assert.Equal(t, readFile(t, path), "second\n")
+}
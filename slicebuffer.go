@@ -2,7 +2,9 @@ package mlog
 
 import (
 	"io"
+	"strconv"
 	"sync"
+	"time"
 )
 
 var bufPool = newSliceBufferPool()
@@ -145,3 +147,172 @@ func (sb *sliceBuffer) Reset() {
 func (sb *sliceBuffer) Truncate(i int) {
 	sb.data = sb.data[:i]
 }
+
+// writeInt writes the decimal form of i to w. Writing to a *sliceBuffer
+// does not allocate.
+func writeInt(w byteSliceWriter, i int64) {
+	if sb, ok := w.(*sliceBuffer); ok {
+		sb.data = strconv.AppendInt(sb.data, i, 10)
+		return
+	}
+	w.WriteString(strconv.FormatInt(i, 10))
+}
+
+// writeUint writes the decimal form of u to w. Writing to a *sliceBuffer
+// does not allocate.
+func writeUint(w byteSliceWriter, u uint64) {
+	if sb, ok := w.(*sliceBuffer); ok {
+		sb.data = strconv.AppendUint(sb.data, u, 10)
+		return
+	}
+	w.WriteString(strconv.FormatUint(u, 10))
+}
+
+// writeFloat writes the shortest decimal form of f to w. Writing to a
+// *sliceBuffer does not allocate.
+func writeFloat(w byteSliceWriter, f float64, bitSize int) {
+	if sb, ok := w.(*sliceBuffer); ok {
+		sb.data = strconv.AppendFloat(sb.data, f, 'g', -1, bitSize)
+		return
+	}
+	w.WriteString(strconv.FormatFloat(f, 'g', -1, bitSize))
+}
+
+// writeBool writes "true" or "false" to w.
+func writeBool(w byteSliceWriter, b bool) {
+	if b {
+		w.WriteString("true")
+	} else {
+		w.WriteString("false")
+	}
+}
+
+// writeDuration writes the string form of d to w, as formatted by
+// time.Duration.String. Writing to a *sliceBuffer does not allocate.
+func writeDuration(w byteSliceWriter, d time.Duration) {
+	if sb, ok := w.(*sliceBuffer); ok {
+		sb.data = appendDuration(sb.data, d)
+		return
+	}
+	var buf [32]byte
+	w.Write(appendDuration(buf[:0], d))
+}
+
+// appendDuration appends the string form of d to b, as formatted by
+// time.Duration.String.
+func appendDuration(b []byte, d time.Duration) []byte {
+	// the largest duration is 2562047h47m16.854775807s, so this fits
+	var buf [32]byte
+	w := len(buf)
+
+	u := uint64(d)
+	neg := d < 0
+	if neg {
+		u = -u
+	}
+
+	if u < uint64(time.Second) {
+		// less than one second: use a smaller unit, such as 1.2ms
+		var prec int
+		w--
+		buf[w] = 's'
+		w--
+		switch {
+		case u == 0:
+			return append(b, "0s"...)
+		case u < uint64(time.Microsecond):
+			prec = 0
+			buf[w] = 'n'
+		case u < uint64(time.Millisecond):
+			prec = 3
+			// U+00B5 'µ' micro sign == 0xC2 0xB5
+			w--
+			copy(buf[w:], "µ")
+		default:
+			prec = 6
+			buf[w] = 'm'
+		}
+		w, u = fmtFrac(buf[:w], u, prec)
+		w = fmtInt(buf[:w], u)
+	} else {
+		w--
+		buf[w] = 's'
+
+		w, u = fmtFrac(buf[:w], u, 9)
+
+		// u is now integer seconds
+		w = fmtInt(buf[:w], u%60)
+		u /= 60
+
+		// u is now integer minutes
+		if u > 0 {
+			w--
+			buf[w] = 'm'
+			w = fmtInt(buf[:w], u%60)
+			u /= 60
+
+			// u is now integer hours
+			if u > 0 {
+				w--
+				buf[w] = 'h'
+				w = fmtInt(buf[:w], u)
+			}
+		}
+	}
+
+	if neg {
+		w--
+		buf[w] = '-'
+	}
+	return append(b, buf[w:]...)
+}
+
+// fmtFrac formats the fraction of v/10**prec (e.g., ".12345") into the tail
+// of buf, omitting trailing zeros. It omits the decimal point too when the
+// fraction is 0. It returns the index where the output bytes begin and the
+// value v/10**prec. It is copied from package time.
+func fmtFrac(buf []byte, v uint64, prec int) (nw int, nv uint64) {
+	w := len(buf)
+	print := false
+	for i := 0; i < prec; i++ {
+		digit := v % 10
+		print = print || digit != 0
+		if print {
+			w--
+			buf[w] = byte(digit) + '0'
+		}
+		v /= 10
+	}
+	if print {
+		w--
+		buf[w] = '.'
+	}
+	return w, v
+}
+
+// fmtInt formats v into the tail of buf. It returns the index where the
+// output begins. It is copied from package time.
+func fmtInt(buf []byte, v uint64) int {
+	w := len(buf)
+	if v == 0 {
+		w--
+		buf[w] = '0'
+	} else {
+		for v > 0 {
+			w--
+			buf[w] = byte(v%10) + '0'
+			v /= 10
+		}
+	}
+	return w
+}
+
+// writeTimeRFC3339 writes t to w in RFC3339 format, with nanoseconds.
+// Writing to a *sliceBuffer does not allocate.
+func writeTimeRFC3339(w byteSliceWriter, t time.Time) {
+	if sb, ok := w.(*sliceBuffer); ok {
+		sb.data = t.AppendFormat(sb.data, time.RFC3339Nano)
+		return
+	}
+	w.WriteString(t.Format(time.RFC3339Nano))
+}
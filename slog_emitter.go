@@ -0,0 +1,90 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mlog
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+)
+
+// SlogEmitter is an Emitter that forwards log events to a slog.Handler,
+// instead of formatting and writing them to the Logger output.
+//
+// The mlog Levels map onto the slog.Level of the same value, with LevelFatal
+// logged as slog.LevelError. Extra Map and Attr elements become
+// slog Attrs. The caller is forwarded as the record PC, if the Logger has the
+// Llongfile or Lshortfile flag.
+type SlogEmitter struct {
+	handler slog.Handler
+}
+
+// NewSlogEmitter creates a new SlogEmitter that forwards to handler.
+func NewSlogEmitter(handler slog.Handler) *SlogEmitter {
+	return &SlogEmitter{handler: handler}
+}
+
+// Emit forwards a log event (with nillable extra Map) to the slog.Handler.
+func (e *SlogEmitter) Emit(logger *Logger, level int, message string, extra Map) {
+	EmitMapRecord(e, logger, level, message, extra)
+}
+
+// EmitAttrs forwards a log event (with optional extra Attrs) to the
+// slog.Handler.
+func (e *SlogEmitter) EmitAttrs(logger *Logger, level int, message string, extra ...*Attr) {
+	EmitAttrsRecord(e, logger, level, message, extra)
+}
+
+// Enabled reports whether the slog.Handler handles events at level.
+func (e *SlogEmitter) Enabled(_ *Logger, level Level) bool {
+	return e.handler.Enabled(context.Background(), levelToSlog(level))
+}
+
+// EmitRecord forwards r to the slog.Handler.
+func (e *SlogEmitter) EmitRecord(logger *Logger, r Record) {
+	ctx := context.Background()
+	level := levelToSlog(r.Level)
+	if !e.handler.Enabled(ctx, level) {
+		return
+	}
+
+	sr := slog.NewRecord(r.Time, level, r.Message, r.PC)
+	for i, n := 0, r.NumAttrs(); i < n; i++ {
+		sr.AddAttrs(slogAttr(r.attr(i)))
+	}
+	_ = e.handler.Handle(ctx, sr)
+}
+
+// slogAttr converts attr to a slog.Attr.
+func slogAttr(attr *Attr) slog.Attr {
+	switch attr.kind {
+	case kindString:
+		return slog.String(attr.Key, attr.str)
+	case kindInt64:
+		return slog.Int64(attr.Key, int64(attr.num))
+	case kindUint64:
+		return slog.Uint64(attr.Key, attr.num)
+	case kindFloat64:
+		return slog.Float64(attr.Key, math.Float64frombits(attr.num))
+	case kindBool:
+		return slog.Bool(attr.Key, attr.num == 1)
+	case kindDuration:
+		return slog.Duration(attr.Key, time.Duration(attr.num))
+	case kindTime:
+		return slog.Time(attr.Key, attr.time())
+	default:
+		return slog.Any(attr.Key, attr.Value)
+	}
+}
+
+// levelToSlog maps an mlog level onto a slog.Level.
+func levelToSlog(level Level) slog.Level {
+	// the Level values match, but slog has no fatal level
+	if level > LevelError {
+		return slog.LevelError
+	}
+	return slog.Level(level)
+}
@@ -0,0 +1,74 @@
+package mlog
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/dropwhile/assert"
+)
+
+func TestSlogEmitter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	handler := slog.NewTextHandler(buf, &slog.HandlerOptions{
+		AddSource: true,
+		Level:     slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			switch a.Key {
+			case slog.TimeKey:
+				return slog.Attr{}
+			case slog.SourceKey:
+				src, _ := a.Value.Any().(*slog.Source)
+				if src == nil || src.File == "" {
+					return slog.Attr{}
+				}
+				return slog.Int("line", src.Line)
+			}
+			return a
+		},
+	})
+	logger := NewFormatLogger(io.Discard, Lsort|Lshortfile|Ldebug, NewSlogEmitter(handler))
+
+	logger.Debugx("test", A("x", "y"))
+	assert.MatchesRegex(t, buf.String(), `^level=DEBUG line=[0-9]+ msg=test x=y\n$`)
+
+	buf.Truncate(0)
+	logger.With(A("a", 1)).Infom("test", Map{"x": "y", "t": "u"})
+	assert.MatchesRegex(t, buf.String(), `^level=INFO line=[0-9]+ msg=test a=1 t=u x=y\n$`)
+
+	buf.Truncate(0)
+	assertPanic(t, func() { logger.Panicf("test %d", 1) })
+	assert.MatchesRegex(t, buf.String(), `^level=ERROR line=[0-9]+ msg="test 1"\n$`)
+
+	// no caller without a file flag
+	buf.Truncate(0)
+	logger.SetFlags(0)
+	logger.Info("test")
+	assert.Equal(t, buf.String(), "level=INFO msg=test\n")
+}
+
+func TestSlogEmitterCaller(t *testing.T) {
+	var source *slog.Source
+	handler := slog.NewTextHandler(io.Discard, &slog.HandlerOptions{
+		AddSource: true,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.SourceKey {
+				source = a.Value.Any().(*slog.Source)
+			}
+			return a
+		},
+	})
+	logger := NewFormatLogger(io.Discard, Llongfile, NewSlogEmitter(handler))
+
+	for _, f := range []func(){
+		func() { logger.Info("test") },
+		func() { logger.Infox("test") },
+		func() { logger.Infom("test", nil) },
+	} {
+		source = nil
+		f()
+		assert.NotNil(t, source)
+		assert.MatchesRegex(t, source.File, `slog_emitter_test.go$`)
+	}
+}
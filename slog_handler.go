@@ -0,0 +1,126 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mlog
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogHandler is a slog.Handler that writes records through a Logger, using
+// the Logger's configured Emitter and FlagSet.
+//
+// Records are logged at the Level of the same value as their slog.Level.
+// Records below slog.LevelInfo are only enabled if the Logger has the Ldebug
+// flag. Records above slog.LevelError are logged as LevelFatal, but do not
+// exit.
+//
+// Attrs in groups are flattened, with the group names joined to the Attr key
+// by a '.', as in "group.key". The record source is logged as the caller, if
+// the Logger has the Llongfile or Lshortfile flag.
+type SlogHandler struct {
+	logger *Logger
+	prefix string
+}
+
+// NewSlogHandler creates a new SlogHandler that writes to logger.
+func NewSlogHandler(logger *Logger) *SlogHandler {
+	return &SlogHandler{logger: logger}
+}
+
+// Enabled reports whether the handler handles records at the given level.
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.Enabled(levelFromSlog(level))
+}
+
+// Handle logs the record r through the Logger, along with any Attrs
+// extracted from ctx. See RegisterContextExtractor.
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	var pc uintptr
+	if h.logger.Flags()&(Lshortfile|Llongfile) != 0 {
+		pc = r.PC
+	}
+
+	var attrs []*Attr
+	if r.NumAttrs() > 0 {
+		attrs = make([]*Attr, 0, r.NumAttrs())
+		r.Attrs(func(a slog.Attr) bool {
+			attrs = appendSlogAttr(attrs, h.prefix, a)
+			return true
+		})
+	}
+
+	record := NewRecord(r.Time, levelFromSlog(r.Level), r.Message, pc)
+	record.AddAttrs(contextAttrs(ctx, attrs)...)
+	h.logger.EmitRecord(record)
+	return nil
+}
+
+// WithAttrs returns a new SlogHandler, whose Logger has attrs bound to it.
+// See Logger.With.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	bound := make([]*Attr, 0, len(attrs))
+	for _, a := range attrs {
+		bound = appendSlogAttr(bound, h.prefix, a)
+	}
+	if len(bound) == 0 {
+		return h
+	}
+	return &SlogHandler{logger: h.logger.With(bound...), prefix: h.prefix}
+}
+
+// WithGroup returns a new SlogHandler that qualifies all subsequent Attr keys
+// with name.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &SlogHandler{logger: h.logger, prefix: h.prefix + name + "."}
+}
+
+// levelFromSlog maps a slog.Level onto one of the mlog levels.
+func levelFromSlog(level slog.Level) Level {
+	// the Level values match
+	return Level(level)
+}
+
+// appendSlogAttr appends a to attrs, with its key qualified by prefix.
+// Groups are flattened, and empty Attrs are skipped.
+func appendSlogAttr(attrs []*Attr, prefix string, a slog.Attr) []*Attr {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return attrs
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix = prefix + a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			attrs = appendSlogAttr(attrs, prefix, ga)
+		}
+		return attrs
+	}
+
+	key := prefix + a.Key
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return append(attrs, String(key, a.Value.String()))
+	case slog.KindInt64:
+		return append(attrs, Int64(key, a.Value.Int64()))
+	case slog.KindUint64:
+		return append(attrs, Uint64(key, a.Value.Uint64()))
+	case slog.KindFloat64:
+		return append(attrs, Float64(key, a.Value.Float64()))
+	case slog.KindBool:
+		return append(attrs, Bool(key, a.Value.Bool()))
+	case slog.KindDuration:
+		return append(attrs, Duration(key, a.Value.Duration()))
+	case slog.KindTime:
+		return append(attrs, Time(key, a.Value.Time()))
+	default:
+		return append(attrs, A(key, a.Value.Any()))
+	}
+}
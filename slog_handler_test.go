@@ -0,0 +1,78 @@
+package mlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/slogtest"
+
+	"github.com/dropwhile/assert"
+)
+
+func TestSlogHandlerSlogtest(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewFormatLogger(buf, Lstd|Ldebug, &FormatWriterJSON{})
+
+	results := func() []map[string]any {
+		ms := []map[string]any{}
+		for _, line := range bytes.Split(buf.Bytes(), []byte{'\n'}) {
+			if len(line) == 0 {
+				continue
+			}
+			var m map[string]any
+			if err := json.Unmarshal(line, &m); err != nil {
+				t.Fatal(err)
+			}
+			// un-flatten grouped keys out of extra
+			if extra, ok := m["extra"].(map[string]any); ok {
+				delete(m, "extra")
+				for k, v := range extra {
+					dst := m
+					keys := strings.Split(k, ".")
+					for _, g := range keys[:len(keys)-1] {
+						sub, ok := dst[g].(map[string]any)
+						if !ok {
+							sub = map[string]any{}
+							dst[g] = sub
+						}
+						dst = sub
+					}
+					dst[keys[len(keys)-1]] = v
+				}
+			}
+			ms = append(ms, m)
+		}
+		return ms
+	}
+
+	err := slogtest.TestHandler(NewSlogHandler(logger), results)
+	assert.Nil(t, err)
+}
+
+func TestSlogHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(buf, Llevel|Lshortfile)
+	slogger := slog.New(NewSlogHandler(logger))
+
+	slogger.Debug("test")
+	assert.Equal(t, buf.String(), "")
+
+	slogger.With("x", "y").WithGroup("g").Info("test", "z", 1)
+	assert.MatchesRegex(t, buf.String(),
+		`^level="I" caller="slog_handler_test.go:[0-9]+" msg="test" x="y" g.z="1"\n$`)
+
+	buf.Truncate(0)
+	slogger.Warn("test")
+	assert.MatchesRegex(t, buf.String(), `^level="W" caller="slog_handler_test.go:[0-9]+" msg="test"\n$`)
+
+	buf.Truncate(0)
+	slogger.Error("test")
+	assert.MatchesRegex(t, buf.String(), `^level="E" caller="slog_handler_test.go:[0-9]+" msg="test"\n$`)
+
+	buf.Truncate(0)
+	logger.SetFlags(Llevel | Ldebug)
+	slogger.Debug("test")
+	assert.Equal(t, buf.String(), "level=\"D\" msg=\"test\"\n")
+}
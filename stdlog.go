@@ -0,0 +1,119 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mlog
+
+import (
+	"bytes"
+	"log"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// NewStdLogger creates a new log.Logger, that logs each line written to it
+// through logger at the given level. This can be used for the ErrorLog of an
+// http.Server, for example.
+//
+// Lines are only logged if the level is enabled for logger. The caller of the
+// log.Logger method is logged as the caller, if logger has the Llongfile or
+// Lshortfile flag.
+func NewStdLogger(logger *Logger, level Level) *log.Logger {
+	w := &stdLogWriter{logger: logger, level: level}
+	std := log.New(w, "", 0)
+	w.std = std
+	return std
+}
+
+// RedirectStdLog sets the output of the standard log package to log each line
+// through logger at the given level, as with NewStdLogger. The prefix and
+// timestamp added by the standard logger are stripped, according to its
+// current flags and prefix. The returned function restores the previous
+// output.
+func RedirectStdLog(logger *Logger, level Level) func() {
+	std := log.Default()
+	prev := std.Writer()
+	std.SetOutput(&stdLogWriter{logger: logger, level: level, std: std})
+	return func() {
+		std.SetOutput(prev)
+	}
+}
+
+// stdLogWriter is the output of a log.Logger, that logs each line through a
+// Logger.
+type stdLogWriter struct {
+	logger *Logger
+	level  Level
+	std    *log.Logger
+}
+
+func (w *stdLogWriter) Write(b []byte) (int, error) {
+	if !w.logger.Enabled(w.level) {
+		return len(b), nil
+	}
+
+	var pc uintptr
+	if w.logger.Flags()&(Lshortfile|Llongfile) != 0 {
+		pc = stdLogCallerPC()
+	}
+
+	msg := stripStdLogHeader(
+		string(bytes.TrimSuffix(b, []byte{'\n'})),
+		w.std.Prefix(), w.std.Flags(),
+	)
+	w.logger.EmitRecord(NewRecord(time.Now(), w.level, msg, pc))
+	return len(b), nil
+}
+
+// stdLogCallerPC returns the program counter of the first caller outside of
+// the log and log/slog packages.
+func stdLogCallerPC() uintptr {
+	var pcs [16]uintptr
+	// skip runtime.Callers, stdLogCallerPC and stdLogWriter.Write
+	n := runtime.Callers(3, pcs[:])
+	for _, pc := range pcs[:n] {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		if !strings.HasPrefix(frame.Function, "log.") &&
+			!strings.HasPrefix(frame.Function, "log/slog.") {
+			return pc
+		}
+	}
+	return 0
+}
+
+// stripStdLogHeader strips the prefix, timestamp and file of a line written
+// by a log.Logger with the given prefix and flags.
+func stripStdLogHeader(line, prefix string, flags int) string {
+	if flags&log.Lmsgprefix == 0 {
+		line = strings.TrimPrefix(line, prefix)
+	}
+
+	if flags&log.Ldate != 0 {
+		line = trimPrefixLen(line, len("2006/01/02 "))
+	}
+	if flags&(log.Ltime|log.Lmicroseconds) != 0 {
+		if flags&log.Lmicroseconds != 0 {
+			line = trimPrefixLen(line, len("15:04:05.000000 "))
+		} else {
+			line = trimPrefixLen(line, len("15:04:05 "))
+		}
+	}
+	if flags&(log.Lshortfile|log.Llongfile) != 0 {
+		if i := strings.Index(line, ": "); i >= 0 {
+			line = line[i+2:]
+		}
+	}
+
+	if flags&log.Lmsgprefix != 0 {
+		line = strings.TrimPrefix(line, prefix)
+	}
+	return line
+}
+
+func trimPrefixLen(s string, n int) string {
+	if len(s) < n {
+		return ""
+	}
+	return s[n:]
+}
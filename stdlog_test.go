@@ -0,0 +1,71 @@
+package mlog
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/dropwhile/assert"
+)
+
+func TestStdLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(buf, Llevel|Lshortfile)
+	std := NewStdLogger(logger, LevelInfo)
+
+	std.Printf("test %d", 1)
+	std.Println("test", 2)
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte{'\n'})
+	assert.Equal(t, len(lines), 2)
+	assert.MatchesRegex(t, string(lines[0]), `^level="I" caller="stdlog_test.go:[0-9]+" msg="test 1"$`)
+	assert.MatchesRegex(t, string(lines[1]), `^level="I" caller="stdlog_test.go:[0-9]+" msg="test 2"$`)
+
+	// debug lines are only logged with Ldebug
+	buf.Truncate(0)
+	NewStdLogger(logger, LevelDebug).Print("test")
+	assert.Equal(t, buf.String(), "")
+	logger.SetFlags(Llevel | Ldebug)
+	NewStdLogger(logger, LevelDebug).Print("test")
+	assert.Equal(t, buf.String(), `level="D" msg="test"`+"\n")
+}
+
+func TestStdLoggerStripHeader(t *testing.T) {
+	var tests = []struct {
+		prefix string
+		flags  int
+	}{
+		{"", 0},
+		{"", log.LstdFlags},
+		{"prefix: ", log.LstdFlags},
+		{"prefix: ", log.LstdFlags | log.Lmsgprefix},
+		{"prefix: ", log.Ldate | log.Lmicroseconds | log.LUTC},
+		{"prefix: ", log.Ltime | log.Lshortfile},
+		{"prefix: ", log.LstdFlags | log.Llongfile | log.Lmsgprefix},
+	}
+
+	buf := &bytes.Buffer{}
+	logger := New(buf, 0)
+	for _, tt := range tests {
+		buf.Truncate(0)
+		std := NewStdLogger(logger, LevelInfo)
+		std.SetPrefix(tt.prefix)
+		std.SetFlags(tt.flags)
+		std.Print("a test: message")
+		assert.Equal(t, buf.String(), `msg="a test: message"`+"\n")
+	}
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New(buf, Llevel|Lshortfile)
+
+	prevFlags := log.Flags()
+	defer log.SetFlags(prevFlags)
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+
+	restore := RedirectStdLog(logger, LevelFatal)
+	log.Print("test")
+	restore()
+
+	assert.MatchesRegex(t, buf.String(), `^level="F" caller="stdlog_test.go:[0-9]+" msg="test"\n$`)
+}
@@ -0,0 +1,346 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Package syslog provides an mlog Emitter that sends log events to a syslog
+// server, formatted as RFC 5424 (with extra Attrs as structured data) or as
+// legacy RFC 3164.
+//
+// Example usage:
+//
+//	e, err := syslog.New(syslog.Options{
+//	    Network:  "udp",
+//	    Address:  "logs.example.com:514",
+//	    Facility: syslog.Local0,
+//	    AppName:  "billing",
+//	})
+//	if err != nil {
+//	    // handle error
+//	}
+//	defer e.Close()
+//	logger := mlog.NewFormatLogger(io.Discard, mlog.Lshortfile, e)
+//
+// The Logger output is not used. mlog levels map onto syslog severities:
+// debug, info, warning, err and crit (for fatal).
+package syslog
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cactus/mlog"
+	"github.com/cactus/mlog/internal/severity"
+)
+
+// Format is the syslog message format.
+type Format int
+
+const (
+	// RFC5424 is the syslog protocol format, with extra Attrs sent as
+	// structured data.
+	RFC5424 Format = iota
+	// RFC3164 is the legacy BSD syslog format, with extra Attrs appended to
+	// the message as key="value" pairs.
+	RFC3164
+)
+
+// Facility is a syslog facility.
+type Facility int
+
+// Syslog facilities.
+const (
+	Kern Facility = iota
+	User
+	Mail
+	Daemon
+	Auth
+	Syslog
+	Lpr
+	News
+	Uucp
+	Cron
+	Authpriv
+	Ftp
+	_ // ntp
+	_ // security
+	_ // console
+	_ // solaris-cron
+	Local0
+	Local1
+	Local2
+	Local3
+	Local4
+	Local5
+	Local6
+	Local7
+)
+
+// DefaultSDID is the default structured data ID of the RFC 5424 element
+// holding the extra Attrs.
+const DefaultSDID = "mlog@32473"
+
+// Options configures an Emitter.
+type Options struct {
+	// Network and Address of the syslog server, as with net.Dial. Network is
+	// unixgram, unix, udp or tcp (or a tcp4/tcp6/udp4/udp6 variant), and
+	// defaults to unixgram. Address defaults to /dev/log. Stream networks
+	// (tcp and unix) use octet counting framing (RFC 6587).
+	Network string
+	Address string
+	// Format is the message format. Defaults to RFC5424.
+	Format Format
+	// Facility is the syslog facility. The zero value Kern is reserved for
+	// the kernel, so it is replaced by User.
+	Facility Facility
+	// AppName is the RFC 5424 APP-NAME, or the RFC 3164 TAG. Defaults to the
+	// program name.
+	AppName string
+	// Hostname defaults to os.Hostname.
+	Hostname string
+	// SDID is the RFC 5424 structured data ID of the element holding the
+	// extra Attrs. Defaults to DefaultSDID.
+	SDID string
+}
+
+// Emitter is an mlog Emitter that sends log events to a syslog server. If
+// sending fails, it reconnects in the background, and drops events until it
+// has reconnected (see mlog.NetWriter). It is safe for concurrent use.
+type Emitter struct {
+	w        *mlog.NetWriter
+	format   Format
+	facility Facility
+	appName  string
+	hostname string
+	sdid     string
+	pid      string
+	framed   bool
+}
+
+var bufPool = sync.Pool{
+	New: func() interface{} { return &bytes.Buffer{} },
+}
+
+// New connects to the syslog server described by opts, and returns an
+// Emitter sending to it.
+func New(opts Options) (*Emitter, error) {
+	if opts.Network == "" {
+		opts.Network = "unixgram"
+	}
+	if opts.Address == "" {
+		opts.Address = "/dev/log"
+	}
+	if opts.Facility <= Kern || opts.Facility > Local7 {
+		opts.Facility = User
+	}
+	if opts.AppName == "" {
+		opts.AppName = filepath.Base(os.Args[0])
+	}
+	if opts.Hostname == "" {
+		opts.Hostname, _ = os.Hostname()
+	}
+	if opts.SDID == "" {
+		opts.SDID = DefaultSDID
+	}
+
+	w, err := mlog.NewNetWriter(opts.Network, opts.Address)
+	if err != nil {
+		return nil, err
+	}
+	return &Emitter{
+		w:        w,
+		format:   opts.Format,
+		facility: opts.Facility,
+		appName:  headerField(opts.AppName, 48),
+		hostname: headerField(opts.Hostname, 255),
+		sdid:     sdName(opts.SDID),
+		pid:      strconv.Itoa(os.Getpid()),
+		framed:   strings.HasPrefix(opts.Network, "tcp") || opts.Network == "unix",
+	}, nil
+}
+
+// Close closes the connection to the syslog server.
+func (e *Emitter) Close() error {
+	return e.w.Close()
+}
+
+// Emit sends a log event (with nillable extra Map). A Logger calls EmitRecord
+// instead.
+func (e *Emitter) Emit(logger *mlog.Logger, level int, message string, extra mlog.Map) {
+	mlog.EmitMapRecord(e, logger, level, message, extra)
+}
+
+// EmitAttrs sends a log event (with optional extra Attrs). A Logger calls
+// EmitRecord instead.
+func (e *Emitter) EmitAttrs(logger *mlog.Logger, level int, message string, extra ...*mlog.Attr) {
+	mlog.EmitAttrsRecord(e, logger, level, message, extra)
+}
+
+// EmitRecord sends r to the syslog server.
+func (e *Emitter) EmitRecord(logger *mlog.Logger, r mlog.Record) {
+	msg := bufPool.Get().(*bytes.Buffer)
+	defer bufPool.Put(msg)
+	msg.Reset()
+
+	if e.format == RFC3164 {
+		e.writeRFC3164(msg, logger, r)
+	} else {
+		e.writeRFC5424(msg, logger, r)
+	}
+
+	if !e.framed {
+		_, _ = e.w.Write(msg.Bytes())
+		return
+	}
+
+	frame := bufPool.Get().(*bytes.Buffer)
+	defer bufPool.Put(frame)
+	frame.Reset()
+	frame.WriteString(strconv.Itoa(msg.Len()))
+	frame.WriteByte(' ')
+	frame.Write(msg.Bytes())
+	_, _ = e.w.Write(frame.Bytes())
+}
+
+// priority returns the PRI value for level.
+func (e *Emitter) priority(level mlog.Level) int {
+	return int(e.facility)*8 + severity.FromLevel(level)
+}
+
+// writeRFC5424 writes r as:
+//
+//	<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD-ID key="value"...] MSG
+func (e *Emitter) writeRFC5424(b *bytes.Buffer, logger *mlog.Logger, r mlog.Record) {
+	fmt.Fprintf(b, "<%d>1 ", e.priority(r.Level))
+	if r.Time.IsZero() {
+		b.WriteByte('-')
+	} else {
+		b.WriteString(r.Time.Format("2006-01-02T15:04:05.000000Z07:00"))
+	}
+	b.WriteByte(' ')
+	b.WriteString(e.hostname)
+	b.WriteByte(' ')
+	b.WriteString(e.appName)
+	b.WriteByte(' ')
+	b.WriteString(e.pid)
+	b.WriteString(" - ")
+
+	caller := callerString(logger, r)
+	if caller == "" && r.NumAttrs() == 0 {
+		b.WriteByte('-')
+	} else {
+		b.WriteByte('[')
+		b.WriteString(e.sdid)
+		if caller != "" {
+			writeSDParam(b, "caller", caller)
+		}
+		r.Attrs(func(attr mlog.Attr) bool {
+			writeSDParam(b, sdName(attr.Key), attr.StringValue())
+			return true
+		})
+		b.WriteByte(']')
+	}
+
+	if r.Message != "" {
+		b.WriteByte(' ')
+		b.WriteString(r.Message)
+	}
+}
+
+// writeRFC3164 writes r as:
+//
+//	<PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG key="value"...
+func (e *Emitter) writeRFC3164(b *bytes.Buffer, logger *mlog.Logger, r mlog.Record) {
+	fmt.Fprintf(b, "<%d>", e.priority(r.Level))
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	b.WriteString(t.Format(time.Stamp))
+	b.WriteByte(' ')
+	b.WriteString(e.hostname)
+	b.WriteByte(' ')
+	b.WriteString(e.appName)
+	b.WriteByte('[')
+	b.WriteString(e.pid)
+	b.WriteString("]: ")
+	b.WriteString(r.Message)
+
+	if caller := callerString(logger, r); caller != "" {
+		b.WriteString(` caller="`)
+		b.WriteString(caller)
+		b.WriteByte('"')
+	}
+	r.Attrs(func(attr mlog.Attr) bool {
+		b.WriteByte(' ')
+		b.WriteString(attr.String())
+		return true
+	})
+}
+
+// callerString returns the "file:line" of the caller of r, or an empty
+// string if r has no caller.
+func callerString(logger *mlog.Logger, r mlog.Record) string {
+	if r.PC == 0 {
+		return ""
+	}
+	file, line := r.Caller()
+	if logger != nil && logger.Flags()&mlog.Lshortfile != 0 {
+		file = filepath.Base(file)
+	}
+	return file + ":" + strconv.Itoa(line)
+}
+
+// writeSDParam writes an RFC 5424 SD-PARAM, escaping '"', '\' and ']' in
+// value.
+func writeSDParam(b *bytes.Buffer, name, value string) {
+	b.WriteByte(' ')
+	b.WriteString(name)
+	b.WriteString(`="`)
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '"', '\\', ']':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+}
+
+// sdName returns s as a valid RFC 5424 SD-NAME: at most 32 printable ascii
+// characters, other than '=', ' ', ']' and '"'.
+func sdName(s string) string {
+	if len(s) > 32 {
+		s = s[:32]
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// headerField returns s as a valid RFC 5424 header field of at most n
+// printable ascii characters, or "-" if s is empty.
+func headerField(s string, n int) string {
+	if s == "" {
+		return "-"
+	}
+	if len(s) > n {
+		s = s[:n]
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,165 @@
+package syslog
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cactus/mlog"
+	"github.com/dropwhile/assert"
+)
+
+var testTime = time.Date(2016, time.January, 11, 12, 13, 14, 15000, time.UTC)
+
+func listenUDP(t *testing.T) net.PacketConn {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	t.Cleanup(func() { pc.Close() })
+	return pc
+}
+
+func readPacket(t *testing.T, pc net.PacketConn) string {
+	t.Helper()
+	buf := make([]byte, 4096)
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	assert.Nil(t, err)
+	return string(buf[:n])
+}
+
+func TestEmitterRFC5424(t *testing.T) {
+	pc := listenUDP(t)
+	e, err := New(Options{
+		Network:  "udp",
+		Address:  pc.LocalAddr().String(),
+		Facility: Local0,
+		AppName:  "my app",
+		Hostname: "host1",
+	})
+	assert.Nil(t, err)
+	defer e.Close()
+	pid := strconv.Itoa(os.Getpid())
+
+	logger := mlog.NewFormatLogger(io.Discard, 0, e).With(mlog.String("svc", "billing"))
+	r := mlog.NewRecord(testTime, mlog.LevelWarn, "hello world", 0)
+	r.AddAttrs(mlog.String("quote", `a"b]c\d`), mlog.Int("n", 1), mlog.Err("bad key=", errors.New("oops")))
+	logger.EmitRecord(r)
+	assert.Equal(t, readPacket(t, pc),
+		`<132>1 2016-01-11T12:13:14.000015Z host1 my_app `+pid+` - `+
+			`[mlog@32473 svc="billing" quote="a\"b\]c\\d" n="1" bad_key_="oops"] hello world`)
+
+	logger = mlog.NewFormatLogger(io.Discard, 0, e)
+	logger.EmitRecord(mlog.NewRecord(time.Time{}, mlog.LevelFatal, "", 0))
+	assert.Equal(t, readPacket(t, pc), `<130>1 - host1 my_app `+pid+` - -`)
+
+	logger.SetFlags(mlog.Ldebug)
+	logger.Debug("debug")
+	assert.MatchesRegex(t, readPacket(t, pc), `^<135>1 \S+ host1 my_app `+pid+` - - debug$`)
+	logger.SetFlags(mlog.Lshortfile | mlog.Lsort)
+	logger.Infom("info", mlog.Map{"b": 2, "a": 1})
+	assert.MatchesRegex(t, readPacket(t, pc),
+		`^<134>1 \S+ host1 my_app `+pid+` - \[mlog@32473 caller="syslog_test.go:\d+" a="1" b="2"\] info$`)
+	logger.Error("error")
+	assert.MatchesRegex(t, readPacket(t, pc), `^<131>1 `)
+}
+
+func TestEmitterRFC3164(t *testing.T) {
+	pc := listenUDP(t)
+	e, err := New(Options{
+		Network:  "udp",
+		Address:  pc.LocalAddr().String(),
+		Format:   RFC3164,
+		AppName:  "app",
+		Hostname: "host1",
+	})
+	assert.Nil(t, err)
+	defer e.Close()
+
+	logger := mlog.NewFormatLogger(io.Discard, 0, e)
+	r := mlog.NewRecord(testTime, mlog.LevelInfo, "hello", 0)
+	r.AddAttrs(mlog.String("a", "b c"))
+	logger.EmitRecord(r)
+	assert.Equal(t, readPacket(t, pc),
+		`<14>Jan 11 12:13:14 host1 app[`+strconv.Itoa(os.Getpid())+`]: hello a="b c"`)
+}
+
+func TestEmitterTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+
+	e, err := New(Options{
+		Network:  "tcp",
+		Address:  ln.Addr().String(),
+		AppName:  "app",
+		Hostname: "host1",
+	})
+	assert.Nil(t, err)
+	defer e.Close()
+
+	conn, err := ln.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	logger := mlog.NewFormatLogger(io.Discard, 0, e)
+	logger.EmitRecord(mlog.NewRecord(testTime, mlog.LevelInfo, "one", 0))
+	logger.EmitRecord(mlog.NewRecord(testTime, mlog.LevelInfo, "two", 0))
+
+	// octet counting framing
+	br := bufio.NewReader(conn)
+	for _, msg := range []string{"one", "two"} {
+		size, err := br.ReadString(' ')
+		assert.Nil(t, err)
+		n, err := strconv.Atoi(strings.TrimSpace(size))
+		assert.Nil(t, err)
+		b := make([]byte, n)
+		_, err = io.ReadFull(br, b)
+		assert.Nil(t, err)
+		assert.True(t, strings.HasSuffix(string(b), " - - "+msg), string(b))
+	}
+}
+
+func TestEmitterReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+
+	e, err := New(Options{Network: "tcp", Address: ln.Addr().String()})
+	assert.Nil(t, err)
+	defer e.Close()
+	conn, err := ln.Accept()
+	assert.Nil(t, err)
+	conn.Close()
+
+	accepted := make(chan net.Conn)
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	// the first writes after the drop may be accepted by the dead connection
+	logger := mlog.NewFormatLogger(io.Discard, 0, e)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		logger.Info("test")
+		select {
+		case conn := <-accepted:
+			conn.Close()
+			return
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("did not reconnect")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,29 @@
+//go:build unix
+
+package syslog
+
+import (
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cactus/mlog"
+	"github.com/dropwhile/assert"
+)
+
+func TestEmitterUnixgram(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+	pc, err := net.ListenPacket("unixgram", path)
+	assert.Nil(t, err)
+	defer pc.Close()
+
+	e, err := New(Options{Address: path, AppName: "app", Hostname: "host1"})
+	assert.Nil(t, err)
+	defer e.Close()
+
+	logger := mlog.NewFormatLogger(io.Discard, 0, e)
+	logger.EmitRecord(mlog.NewRecord(time.Time{}, mlog.LevelInfo, "hello", 0))
+	assert.MatchesRegex(t, readPacket(t, pc), `^<14>1 - host1 app \d+ - - hello$`)
+}
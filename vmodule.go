@@ -0,0 +1,155 @@
+// Copyright (c) 2012-2023 Eli Janssen
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mlog
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// debugPatterns holds the current debug pattern set, or nil if no pattern
+// is set.
+var debugPatterns atomic.Pointer[debugPatternSet]
+
+// debugRule is a single glob=on|off element of a debug pattern.
+type debugRule struct {
+	glob string
+	on   bool
+}
+
+// debugPatternSet is a parsed debug pattern, along with the cached decision
+// for each call site it has been checked for.
+type debugPatternSet struct {
+	rules []debugRule
+	// maps a caller PC to a bool
+	cache sync.Map
+}
+
+// SetDebugPattern enables debug logging for the call sites matching pattern,
+// for all Loggers, even if the Logger does not have the Ldebug flag or a
+// minimum level of LevelDebug.
+//
+// The pattern is a comma separated list of glob=value elements, where value
+// is on or off, as in:
+//
+//	github.com/acme/billing/*=on,cache.go=on,github.com/acme/billing/noisy.go=off
+//
+// A glob containing a '/' is matched against the package path of the caller
+// and the package path joined with the file name of the caller, such as
+// "github.com/acme/billing" and "github.com/acme/billing/invoice.go". Other
+// globs are matched against the file name only. Globs use the syntax of
+// path.Match, and the first matching element applies.
+//
+// The decision for each call site is cached, so the cost of a Debug call
+// that is not enabled is a caller lookup. An empty pattern disables pattern
+// matching.
+func SetDebugPattern(pattern string) error {
+	if pattern == "" {
+		debugPatterns.Store(nil)
+		return nil
+	}
+
+	set := &debugPatternSet{}
+	for _, elem := range strings.Split(pattern, ",") {
+		elem = strings.TrimSpace(elem)
+		if elem == "" {
+			continue
+		}
+		glob, value, ok := strings.Cut(elem, "=")
+		if !ok || glob == "" {
+			return fmt.Errorf("mlog: invalid debug pattern element %q", elem)
+		}
+		if _, err := path.Match(glob, ""); err != nil {
+			return fmt.Errorf("mlog: invalid debug pattern glob %q: %w", glob, err)
+		}
+
+		rule := debugRule{glob: glob}
+		switch strings.ToLower(value) {
+		case "on", "true", "1":
+			rule.on = true
+		case "off", "false", "0":
+		default:
+			return fmt.Errorf("mlog: invalid debug pattern value %q", value)
+		}
+		set.rules = append(set.rules, rule)
+	}
+
+	debugPatterns.Store(set)
+	return nil
+}
+
+// debugEnabled returns true if LevelDebug is enabled for the Logger, or for
+// the caller of the Logger method calling debugEnabled. See SetDebugPattern.
+func (l *Logger) debugEnabled() bool {
+	if l.Enabled(LevelDebug) {
+		return true
+	}
+
+	set := debugPatterns.Load()
+	if set == nil {
+		return false
+	}
+	// skip debugEnabled and the Logger method
+	if !set.enabled(callerPC(2)) {
+		return false
+	}
+	if lf, ok := l.Emitter().(LevelFilter); ok {
+		return lf.Enabled(l, LevelDebug)
+	}
+	return true
+}
+
+// enabled returns true if debug logging is enabled for the call site at pc.
+func (set *debugPatternSet) enabled(pc uintptr) bool {
+	if on, ok := set.cache.Load(pc); ok {
+		return on.(bool)
+	}
+	on := set.match(pc)
+	set.cache.Store(pc, on)
+	return on
+}
+
+func (set *debugPatternSet) match(pc uintptr) bool {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if frame.File == "" {
+		return false
+	}
+
+	file := path.Base(frame.File)
+	pkg := funcPackage(frame.Function)
+	for _, rule := range set.rules {
+		var matched bool
+		if strings.Contains(rule.glob, "/") {
+			matched, _ = path.Match(rule.glob, pkg)
+			if !matched {
+				matched, _ = path.Match(rule.glob, pkg+"/"+file)
+			}
+		} else {
+			matched, _ = path.Match(rule.glob, file)
+		}
+		if matched {
+			return rule.on
+		}
+	}
+	return false
+}
+
+// funcPackage returns the package path of a fully qualified function name,
+// such as "github.com/acme/billing.(*Invoice).Total".
+func funcPackage(name string) string {
+	dir := ""
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		dir, name = name[:i+1], name[i+1:]
+	}
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		name = name[:i]
+	}
+	// dots in the last element of the package path are escaped
+	return dir + strings.ReplaceAll(name, "%2e", ".")
+}
@@ -0,0 +1,94 @@
+package mlog
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dropwhile/assert"
+)
+
+func TestSetDebugPattern(t *testing.T) {
+	defer SetDebugPattern("")
+
+	var tests = []struct {
+		pattern string
+		enabled bool
+	}{
+		{"", false},
+		{"vmodule_test.go=on", true},
+		{"vmodule_*.go=on", true},
+		{"vmodule_test.go=off", false},
+		{"logger.go=on", false},
+		{"github.com/cactus/mlog=on", true},
+		{"github.com/cactus/mlog/*=true", true},
+		{"github.com/cactus/mlog/vmodule_test.go=1", true},
+		{"github.com/cactus/*=on", true},
+		{"github.com/*=on", false},
+		{"github.com/cactus/mlog/logger.go=on", false},
+		// the first matching element applies
+		{"vmodule_test.go=off,github.com/cactus/mlog/*=on", false},
+		{" logger.go=on , vmodule_test.go=on ", true},
+	}
+
+	buf := &bytes.Buffer{}
+	logger := New(buf, 0)
+	for _, tt := range tests {
+		buf.Truncate(0)
+		assert.Nil(t, SetDebugPattern(tt.pattern))
+		logger.Debugx("test")
+		logger.Debugf("test %d", 1)
+		logger.With(String("x", "y")).Debug("test")
+		if tt.enabled {
+			assert.Equal(t, buf.String(), "msg=\"test\"\nmsg=\"test 1\"\nmsg=\"test\" x=\"y\"\n", tt.pattern)
+		} else {
+			assert.Equal(t, buf.String(), "", tt.pattern)
+		}
+	}
+}
+
+func TestSetDebugPatternCached(t *testing.T) {
+	defer SetDebugPattern("")
+
+	buf := &bytes.Buffer{}
+	logger := New(buf, 0)
+	assert.Nil(t, SetDebugPattern("vmodule_test.go=on"))
+	for i := 0; i < 2; i++ {
+		logger.Debugx("test")
+	}
+	assert.Equal(t, buf.String(), "msg=\"test\"\nmsg=\"test\"\n")
+
+	n := 0
+	debugPatterns.Load().cache.Range(func(_, _ any) bool {
+		n++
+		return true
+	})
+	assert.Equal(t, n, 1)
+}
+
+func TestSetDebugPatternInvalid(t *testing.T) {
+	defer SetDebugPattern("")
+
+	for _, pattern := range []string{
+		"vmodule_test.go",
+		"=on",
+		"vmodule_test.go=maybe",
+		"[=on",
+	} {
+		assert.NotNil(t, SetDebugPattern(pattern), pattern)
+	}
+}
+
+func TestFuncPackage(t *testing.T) {
+	var tests = []struct {
+		name     string
+		expected string
+	}{
+		{"main.main", "main"},
+		{"github.com/acme/billing.(*Invoice).Total", "github.com/acme/billing"},
+		{"github.com/acme/billing.Total.func1", "github.com/acme/billing"},
+		{"gopkg.in/yaml%2ev3.Marshal", "gopkg.in/yaml.v3"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, funcPackage(tt.name), tt.expected)
+	}
+}